@@ -0,0 +1,173 @@
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditEntry records an execution. Arguments, output and errors have their
+// secrets redacted.
+type AuditEntry struct {
+	Time        time.Time `json:"time"`
+	CommandLine string    `json:"command_line"`
+	// User is the account the command ran as.
+	User     string        `json:"user"`
+	Dir      string        `json:"dir"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	// Output holds the start of stdout and stderr, see AuditOptions.
+	Output string `json:"output,omitempty"`
+	// OutputSHA256 is the hash of the whole output, which allows checking it
+	// against a copy kept elsewhere.
+	OutputSHA256 string `json:"output_sha256"`
+}
+
+type AuditSink interface {
+	Record(entry AuditEntry) error
+}
+
+type AuditOptions struct {
+	// OutputBytes is how much of the output is kept in the entries, none when
+	// zero.
+	OutputBytes int
+}
+
+type auditFactory struct {
+	inner CommandFactory
+	sink  AuditSink
+	opts  AuditOptions
+}
+
+// NewAuditFactory records every execution of the commands of inner to sink.
+// Failing to record an entry fails the execution.
+func NewAuditFactory(inner CommandFactory, sink AuditSink, opts AuditOptions) CommandFactory {
+	return &auditFactory{inner: inner, sink: sink, opts: opts}
+}
+
+func (f *auditFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(f.audit))
+}
+
+func (f *auditFactory) audit(next execFunc) execFunc {
+	return func(req *commandRequest, s *streams) error {
+		savedStdout, savedStderr := s.stdout, s.stderr
+		defer func() { s.stdout, s.stderr = savedStdout, savedStderr }()
+		output := &auditOutput{digest: sha256.New(), head: newHeadBuffer(req, f.opts.OutputBytes)}
+		s.tee(output, output)
+
+		clock := req.clockOrDefault()
+		start := clock.Now()
+		err := next(req, s)
+		entry := AuditEntry{
+			Time:         start,
+			CommandLine:  Join(append([]string{req.cmd}, req.redactedArgs()...)...),
+			User:         auditUser(req.credential),
+			Dir:          req.dir,
+			Duration:     clock.Now().Sub(start),
+			OutputSHA256: hex.EncodeToString(output.digest.Sum(nil)),
+		}
+		if entry.Dir == "" {
+			entry.Dir, _ = os.Getwd()
+		}
+		if f.opts.OutputBytes > 0 {
+			entry.Output = output.head.text(req, f.opts.OutputBytes)
+		}
+		if err != nil {
+			entry.ExitCode = -1
+			var cmdErr *CommandError
+			if errors.As(err, &cmdErr) {
+				entry.ExitCode = cmdErr.ExitCode()
+			}
+			entry.Error = string(req.redact([]byte(err.Error())))
+		}
+		if recordErr := f.sink.Record(entry); recordErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to record audit entry: %w", recordErr))
+		}
+		return err
+	}
+}
+
+// auditOutput hashes and keeps the start of both streams, which can be
+// written concurrently.
+type auditOutput struct {
+	mu     sync.Mutex
+	digest hash.Hash
+	head   *headBuffer
+}
+
+func (o *auditOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.digest.Write(p)
+	return o.head.Write(p)
+}
+
+var currentUser = sync.OnceValue(func() string {
+	if account, err := user.Current(); err == nil {
+		return account.Username
+	}
+	return strconv.Itoa(os.Geteuid())
+})
+
+func auditUser(cred *credential) string {
+	if cred == nil {
+		return currentUser()
+	}
+	uid := strconv.FormatUint(uint64(cred.uid), 10)
+	if account, err := user.LookupId(uid); err == nil {
+		return account.Username
+	}
+	return uid
+}
+
+// JSONLAuditSink writes each entry as a line of JSON.
+type JSONLAuditSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+func NewJSONLAuditSink(w io.Writer) *JSONLAuditSink {
+	return &JSONLAuditSink{writer: w}
+}
+
+// NewFileAuditSink appends the entries as JSON lines to the file at path,
+// created readable by its owner only if it does not exist.
+func NewFileAuditSink(path string) (*JSONLAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &JSONLAuditSink{writer: file, closer: file}, nil
+}
+
+func (s *JSONLAuditSink) Record(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.writer.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file of NewFileAuditSink, it does nothing for writers.
+func (s *JSONLAuditSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
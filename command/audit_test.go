@@ -0,0 +1,101 @@
+package command_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+type auditEntries []command.AuditEntry
+
+func (e *auditEntries) Record(entry command.AuditEntry) error {
+	*e = append(*e, entry)
+	return nil
+}
+
+type failingSink struct{}
+
+func (failingSink) Record(command.AuditEntry) error {
+	return errors.New("sink down")
+}
+
+func TestNewAuditFactory(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := commandtest.NewFakeClock(start)
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		clock.Advance(2 * time.Second)
+		if _, err := io.WriteString(inv.Stdout, "password=s3cret accepted"); err != nil {
+			return err
+		}
+		return &command.ExitStatusError{Code: 5}
+	})
+	var entries auditEntries
+	factory := command.NewAuditFactory(inner, &entries, command.AuditOptions{OutputBytes: 9})
+	err := factory.Command(context.Background(), "login", "s3cret").
+		With(command.WithSecretArgs(0), command.WithDir("/srv"), command.WithClock(clock)).Run()
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected an entry, got %v", entries)
+	}
+	entry := entries[0]
+	digest := sha256.Sum256([]byte("password=s3cret accepted"))
+	if entry.Time != start || entry.Duration != 2*time.Second || entry.CommandLine != "login '***'" || entry.Dir != "/srv" ||
+		entry.ExitCode != 5 || entry.Error == "" || entry.User == "" || entry.OutputSHA256 != hex.EncodeToString(digest[:]) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Output != "password=..." {
+		t.Errorf("expected the redacted start of the output, got %q", entry.Output)
+	}
+}
+
+func TestNewAuditFactorySinkFailure(t *testing.T) {
+	factory := command.NewAuditFactory(command.NewFuncFactory(func(*command.Invocation) error { return nil }), failingSink{}, command.AuditOptions{})
+	if err := factory.Command(context.Background(), "true").Run(); err == nil || !strings.Contains(err.Error(), "sink down") {
+		t.Errorf("expected the sink failure to fail the execution, got %v", err)
+	}
+}
+
+func TestNewFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for range 2 {
+		sink, err := command.NewFileAuditSink(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := sink.Record(command.AuditEntry{CommandLine: "ls", ExitCode: 1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file to be readable by its owner only, got %v", info.Mode())
+	}
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the entries to be appended, got %q", content)
+	}
+	var entry command.AuditEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || entry.CommandLine != "ls" || entry.ExitCode != 1 {
+		t.Errorf("expected a JSON entry, got %q and %v", lines[1], err)
+	}
+}
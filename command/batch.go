@@ -0,0 +1,99 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrBatchAborted = errors.New("batch aborted after a failure")
+
+type BatchOptions struct {
+	// Concurrency bounds the commands running at once, zero runs all of them
+	// concurrently.
+	Concurrency int
+	// FailFast cancels the remaining commands after the first failure instead
+	// of running all of them.
+	FailFast bool
+}
+
+type BatchResult struct {
+	Result *Result
+	Err    error
+}
+
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("batch command %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// RunAll executes runnables concurrently and returns their results in the
+// same order. The returned error joins a BatchItemError for each failed
+// command. Commands stopped by the cancellation of FailFast are only reported
+// in their result, the ones failing on their own meanwhile are still joined,
+// and the ones never started get ErrBatchAborted. Once ctx is done, the
+// commands not started yet are reported failed with its cause.
+func RunAll(ctx context.Context, runnables []Runnable, opts BatchOptions) ([]BatchResult, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = len(runnables)
+	}
+	results := make([]BatchResult, len(runnables))
+	errs := make([]error, len(runnables))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for index, runnable := range runnables {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if cause := context.Cause(ctx); cause != nil {
+			results[index].Err = cause
+			if !errors.Is(cause, ErrBatchAborted) {
+				errs[index] = &BatchItemError{Index: index, Err: cause}
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := runnable.With(withCancel(ctx)).Execute()
+			results[index] = BatchResult{Result: result, Err: err}
+			if err == nil || abortedByBatch(ctx, err) {
+				return
+			}
+			errs[index] = &BatchItemError{Index: index, Err: err}
+			if opts.FailFast {
+				cancel(ErrBatchAborted)
+			}
+		}()
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
+
+// abortedByBatch reports whether err comes from the cancellation of a FailFast
+// batch. Commands that exited with a code of their own failed by themselves,
+// even when the batch was aborted meanwhile.
+func abortedByBatch(ctx context.Context, err error) bool {
+	if !errors.Is(context.Cause(ctx), ErrBatchAborted) {
+		return false
+	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode() < 0
+	}
+	return errors.Is(err, ErrBatchAborted) || errors.Is(err, context.Canceled)
+}
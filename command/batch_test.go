@@ -0,0 +1,104 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// batchItemIndexes returns the indexes of the BatchItemError values joined in
+// err.
+func batchItemIndexes(err error) []int {
+	indexes := []int{}
+	joined, _ := err.(interface{ Unwrap() []error })
+	if joined == nil {
+		return indexes
+	}
+	for _, item := range joined.Unwrap() {
+		var itemErr *command.BatchItemError
+		if errors.As(item, &itemErr) {
+			indexes = append(indexes, itemErr.Index)
+		}
+	}
+	return indexes
+}
+
+func TestRunAll(t *testing.T) {
+	runnables := []command.Runnable{outputCommand("a"), outputCommand("b"), outputCommand("c")}
+	results, err := command.RunAll(context.Background(), runnables, command.BatchOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for index, expected := range []string{"a", "b", "c"} {
+		if results[index].Err != nil || string(results[index].Result.Stdout) != expected {
+			t.Errorf("%d: unexpected result %+v", index, results[index])
+		}
+	}
+}
+
+func TestRunAllReportsEveryFailure(t *testing.T) {
+	failing, _ := flakyCommand(t, 1, 2)
+	runnables := []command.Runnable{outputCommand("a"), failing, outputCommand("c")}
+	results, err := command.RunAll(context.Background(), runnables, command.BatchOptions{})
+	if indexes := batchItemIndexes(err); len(indexes) != 1 || indexes[0] != 1 {
+		t.Fatalf("expected the failure of command 1, got %v", err)
+	}
+	if results[1].Result.ExitCode != 2 || string(results[2].Result.Stdout) != "c" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestRunAllWithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, attempts := flakyCommand(t, 0, 0)
+	results, err := command.RunAll(ctx, []command.Runnable{r, r}, command.BatchOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation to be reported, got %v", err)
+	}
+	if indexes := batchItemIndexes(err); len(indexes) != 2 {
+		t.Errorf("expected both commands to be reported, got %v", indexes)
+	}
+	if attempts() != 0 || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("expected no command to run, got %d executions and %+v", attempts(), results)
+	}
+}
+
+func TestRunAllFailFastKeepsConcurrentFailures(t *testing.T) {
+	dir := t.TempDir()
+	factory := command.NewExecCmdFactory()
+	runnables := []command.Runnable{
+		// Waits for the other commands, so they fail after the batch abort.
+		factory.Command(context.Background(), "sh", "-c", `until [ -e "$0/exit" ] && [ -e "$0/wait" ]; do sleep 0.01; done; exit 1`, dir),
+		factory.Command(context.Background(), "sh", "-c", `trap 'exit 3' TERM; touch "$0/exit"; while :; do sleep 0.01; done`, dir).
+			With(command.WithGracefulStop(syscall.SIGTERM, time.Minute)),
+		factory.Command(context.Background(), "sh", "-c", `touch "$0/wait"; exec sleep 30`, dir),
+	}
+	results, err := command.RunAll(context.Background(), runnables, command.BatchOptions{FailFast: true})
+	if indexes := batchItemIndexes(err); len(indexes) != 2 || indexes[0] != 0 || indexes[1] != 1 {
+		t.Errorf("expected failures of commands 0 and 1, got %v", indexes)
+	}
+	var cmdErr *command.CommandError
+	if !errors.As(results[1].Err, &cmdErr) || cmdErr.ExitCode() != 3 {
+		t.Errorf("expected the exit code of the interrupted command, got %v", results[1].Err)
+	}
+	if results[2].Err == nil {
+		t.Error("expected the aborted command to report its failure")
+	}
+}
+
+func TestRunAllFailFastSkipsUnstartedCommands(t *testing.T) {
+	failing, _ := flakyCommand(t, 1, 1)
+	r, attempts := flakyCommand(t, 0, 0)
+	results, err := command.RunAll(context.Background(), []command.Runnable{failing, r}, command.BatchOptions{Concurrency: 1, FailFast: true})
+	if indexes := batchItemIndexes(err); len(indexes) != 1 || indexes[0] != 0 {
+		t.Errorf("expected only the failure of command 0, got %v", err)
+	}
+	if attempts() != 0 || !errors.Is(results[1].Err, command.ErrBatchAborted) {
+		t.Errorf("expected the second command not to run, got %d executions and %+v", attempts(), results[1])
+	}
+}
@@ -0,0 +1,91 @@
+package command_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+var benchOutput = strings.Repeat("output line\n", 256)
+
+func benchFuncFactory() command.CommandFactory {
+	return command.NewFuncFactory(func(inv *command.Invocation) error {
+		_, err := io.WriteString(inv.Stdout, benchOutput)
+		return err
+	})
+}
+
+func BenchmarkRun(b *testing.B) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "true")
+	b.ReportAllocs()
+	for range b.N {
+		if err := r.Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRunFailing(b *testing.B) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo failed >&2; exit 1")
+	b.ReportAllocs()
+	for range b.N {
+		if err := r.Run(); err == nil {
+			b.Fatal("expected the command to fail")
+		}
+	}
+}
+
+func BenchmarkRunWithEnv(b *testing.B) {
+	r := command.NewExecCmdFactory(command.WithDefaultEnv(map[string]string{"A": "1", "B": "2"})).Command(context.Background(), "true")
+	b.ReportAllocs()
+	for range b.N {
+		if err := r.Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRunStdoutStr(b *testing.B) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "echo", "hello")
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := r.RunStdoutStr(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The func factory benchmarks leave the process out, measuring the overhead
+// of the package itself.
+func BenchmarkFuncRunStdoutStr(b *testing.B) {
+	r := benchFuncFactory().Command(context.Background(), "output")
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := r.RunStdoutStr(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFuncRunCombinedStr(b *testing.B) {
+	r := benchFuncFactory().Command(context.Background(), "output")
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := r.RunCombinedStr(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFuncRunLines(b *testing.B) {
+	r := benchFuncFactory().Command(context.Background(), "output")
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := r.RunLines(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
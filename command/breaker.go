@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit, 5 when zero.
+	FailureThreshold int
+	// OpenDuration is how long the circuit rejects executions before letting
+	// probes through, 30 seconds when zero.
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of executions allowed while probing, all
+	// of them must succeed to close the circuit. One when zero.
+	HalfOpenProbes int
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuitBreakerFactory struct {
+	inner    CommandFactory
+	settings CircuitBreakerSettings
+
+	mu        sync.Mutex
+	state     circuitState
+	failures  int
+	openUntil time.Time
+	probes    int
+	successes int
+}
+
+// NewCircuitBreakerFactory returns a factory failing fast with ErrCircuitOpen
+// once the commands it creates keep failing. Executions canceled through their
+// own context are not counted as failures. The circuit stays open for the
+// clock of the commands, which should all share the same one.
+func NewCircuitBreakerFactory(inner CommandFactory, settings CircuitBreakerSettings) CommandFactory {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 5
+	}
+	if settings.OpenDuration <= 0 {
+		settings.OpenDuration = 30 * time.Second
+	}
+	if settings.HalfOpenProbes <= 0 {
+		settings.HalfOpenProbes = 1
+	}
+	return &circuitBreakerFactory{inner: inner, settings: settings}
+}
+
+func (f *circuitBreakerFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			clock := req.clockOrDefault()
+			if !f.allow(clock.Now()) {
+				return ErrCircuitOpen
+			}
+			err := next(req, s)
+			f.record(clock.Now(), err == nil, err != nil && req.ctx.Err() != nil)
+			return err
+		}
+	}))
+}
+
+func (f *circuitBreakerFactory) allow(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state == circuitOpen {
+		if now.Before(f.openUntil) {
+			return false
+		}
+		f.state, f.probes, f.successes = circuitHalfOpen, 0, 0
+	}
+	if f.state == circuitHalfOpen {
+		if f.probes >= f.settings.HalfOpenProbes {
+			return false
+		}
+		f.probes++
+	}
+	return true
+}
+
+func (f *circuitBreakerFactory) record(now time.Time, success bool, canceled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if canceled {
+		if f.state == circuitHalfOpen {
+			f.probes--
+		}
+		return
+	}
+	switch {
+	case f.state == circuitHalfOpen && success:
+		if f.successes++; f.successes >= f.settings.HalfOpenProbes {
+			f.state, f.failures = circuitClosed, 0
+		}
+	case f.state == circuitHalfOpen:
+		f.open(now)
+	case success:
+		f.failures = 0
+	default:
+		if f.failures++; f.failures >= f.settings.FailureThreshold {
+			f.open(now)
+		}
+	}
+}
+
+func (f *circuitBreakerFactory) open(now time.Time) {
+	f.state = circuitOpen
+	f.openUntil = now.Add(f.settings.OpenDuration)
+}
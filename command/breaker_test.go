@@ -0,0 +1,91 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+type breakerFixture struct {
+	clock   *commandtest.FakeClock
+	factory command.CommandFactory
+	fail    bool
+	log     runLog
+}
+
+func newBreakerFixture(t *testing.T, threshold int) *breakerFixture {
+	f := &breakerFixture{clock: commandtest.NewFakeClock(time.Now()), log: newRunLog(t)}
+	f.factory = clockedFactory{command.NewCircuitBreakerFactory(f, command.CircuitBreakerSettings{
+		FailureThreshold: threshold,
+		OpenDuration:     time.Minute,
+	}), f.clock}
+	return f
+}
+
+// Command runs a probe recording its runs, failing while f.fail is set.
+func (f *breakerFixture) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	script := "exit 0"
+	if f.fail {
+		script = "exit 1"
+	}
+	return f.log.command(ctx, cmd, script, args...)
+}
+
+func (f *breakerFixture) run() error {
+	return f.factory.Command(context.Background(), "probe").Run()
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	f := newBreakerFixture(t, 2)
+	f.fail = true
+	_ = f.run()
+	f.fail = false
+	_ = f.run()
+	f.fail = true
+	_ = f.run()
+	if f.log.runs() != 3 {
+		t.Fatalf("expected a success to reset the failures, ran %d times", f.log.runs())
+	}
+	_ = f.run()
+	if err := f.run(); !errors.Is(err, command.ErrCircuitOpen) {
+		t.Errorf("expected the circuit to be open, got %v", err)
+	}
+	if f.log.runs() != 4 {
+		t.Errorf("expected the open circuit to reject the command, ran %d times", f.log.runs())
+	}
+}
+
+func TestCircuitBreakerProbes(t *testing.T) {
+	f := newBreakerFixture(t, 1)
+	f.fail = true
+	_ = f.run()
+	f.clock.Advance(time.Minute + time.Second)
+	if err := f.run(); err == nil || errors.Is(err, command.ErrCircuitOpen) {
+		t.Fatalf("expected the probe to run and fail, got %v", err)
+	}
+	if err := f.run(); !errors.Is(err, command.ErrCircuitOpen) {
+		t.Fatalf("expected a failed probe to open the circuit again, got %v", err)
+	}
+	f.clock.Advance(time.Minute + time.Second)
+	f.fail = false
+	if err := f.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.run(); err != nil {
+		t.Errorf("expected a successful probe to close the circuit, got %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresCanceledExecutions(t *testing.T) {
+	f := newBreakerFixture(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = f.factory.Command(ctx, "probe").Run()
+	if err := f.run(); err != nil {
+		t.Errorf("expected canceled executions not to open the circuit, got %v", err)
+	}
+}
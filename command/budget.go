@@ -0,0 +1,28 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var ErrExecutionLimit = errors.New("execution limit reached")
+
+// WithMaxExecutions fails the executions of the commands of the factory with
+// ErrExecutionLimit once n of them started, which stops loops running
+// commands without bound. Each factory built with the option has its own
+// count. Retried attempts count as executions, and so do the ones failing to
+// start, like those of a missing executable.
+func WithMaxExecutions(n int) FactoryOption {
+	return func(f *execCmdFactory) {
+		started := new(atomic.Int64)
+		WithDefaultOptions(withMiddleware(func(next execFunc) execFunc {
+			return func(req *commandRequest, s *streams) error {
+				if started.Add(1) > int64(n) {
+					return fmt.Errorf("%w: %d executions already started", ErrExecutionLimit, n)
+				}
+				return next(req, s)
+			}
+		}))(f)
+	}
+}
@@ -0,0 +1,38 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithMaxExecutions(t *testing.T) {
+	factory := command.NewExecCmdFactory(command.WithMaxExecutions(3))
+	r := factory.Command(context.Background(), "sh", "-c", "exit 1")
+	err := command.WithRetry(r, command.RetryPolicy{MaxAttempts: 2}).Run()
+	if code, ok := command.ExitCode(err); !ok || code != 1 {
+		t.Fatalf("expected both attempts to run, got %v", err)
+	}
+	if err := factory.Command(context.Background(), "true").Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := factory.Command(context.Background(), "true").Run(); !errors.Is(err, command.ErrExecutionLimit) {
+		t.Errorf("expected ErrExecutionLimit once the retried attempts and the other command ran, got %v", err)
+	}
+}
+
+func TestWithMaxExecutionsCountsPerFactory(t *testing.T) {
+	option := command.WithMaxExecutions(1)
+	first, second := command.NewExecCmdFactory(option), command.NewExecCmdFactory(option)
+	if err := first.Command(context.Background(), "no-such-command-in-path").Run(); err == nil {
+		t.Fatal("expected the missing executable to fail")
+	}
+	if err := first.Command(context.Background(), "true").Run(); !errors.Is(err, command.ErrExecutionLimit) {
+		t.Errorf("expected the failed start to count, got %v", err)
+	}
+	if err := second.Command(context.Background(), "true").Run(); err != nil {
+		t.Errorf("expected the other factory to have its own count, got %v", err)
+	}
+}
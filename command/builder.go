@@ -0,0 +1,57 @@
+package command
+
+import (
+	"context"
+	"time"
+)
+
+type CommandBuilder struct {
+	ctx     context.Context
+	factory CommandFactory
+	cmd     string
+	args    []string
+	opts    []Option
+}
+
+func NewBuilder(ctx context.Context, cmd string) *CommandBuilder {
+	return &CommandBuilder{ctx: ctx, cmd: cmd}
+}
+
+func (b *CommandBuilder) Factory(factory CommandFactory) *CommandBuilder {
+	b.factory = factory
+	return b
+}
+
+func (b *CommandBuilder) Args(args ...string) *CommandBuilder {
+	b.args = append(b.args, args...)
+	return b
+}
+
+func (b *CommandBuilder) Dir(dir string) *CommandBuilder {
+	return b.Options(WithDir(dir))
+}
+
+func (b *CommandBuilder) Env(key, value string) *CommandBuilder {
+	return b.Options(WithEnv(map[string]string{key: value}))
+}
+
+func (b *CommandBuilder) EnvInherit(inherit bool) *CommandBuilder {
+	return b.Options(WithEnvInherit(inherit))
+}
+
+func (b *CommandBuilder) Timeout(timeout time.Duration) *CommandBuilder {
+	return b.Options(WithTimeout(timeout))
+}
+
+func (b *CommandBuilder) Options(opts ...Option) *CommandBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+func (b *CommandBuilder) Build() Runnable {
+	factory := b.factory
+	if factory == nil {
+		factory = NewExecCmdFactory()
+	}
+	return factory.Command(b.ctx, b.cmd, append([]string(nil), b.args...)...).With(b.opts...)
+}
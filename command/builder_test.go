@@ -0,0 +1,40 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestBuilder(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := command.NewBuilder(context.Background(), "sh").
+		Args("-c", `echo "$(pwd -P) $BUILDER_VAR $*"`, "sh", "log").
+		Dir(dir).
+		Env("BUILDER_VAR", "cat")
+	first := builder.Build()
+	second := builder.Args("-n").Build()
+
+	output, err := first.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != dir+" cat log\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	// Built Runnables are not affected by later calls to the builder.
+	output, err = second.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != dir+" cat log -n\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+}
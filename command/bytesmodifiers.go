@@ -0,0 +1,89 @@
+package command
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type gunzipPostModifier struct{}
+
+// NewGunzipPostModifier decompresses gzip output.
+func NewGunzipPostModifier() *gunzipPostModifier {
+	return &gunzipPostModifier{}
+}
+
+func (m *gunzipPostModifier) processBytes(content []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return content, fmt.Errorf("failed to decompress output: %w", err)
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return content, fmt.Errorf("failed to decompress output: %w", err)
+	}
+	return decompressed, nil
+}
+
+type Base64Encoding int
+
+const (
+	Base64Std Base64Encoding = iota
+	Base64URL
+	Base64RawStd
+	Base64RawURL
+)
+
+func (e Base64Encoding) encoding() *base64.Encoding {
+	switch e {
+	case Base64URL:
+		return base64.URLEncoding
+	case Base64RawStd:
+		return base64.RawStdEncoding
+	case Base64RawURL:
+		return base64.RawURLEncoding
+	default:
+		return base64.StdEncoding
+	}
+}
+
+type base64DecodePostModifier struct {
+	encoding Base64Encoding
+}
+
+// NewBase64DecodePostModifier decodes base64 output, whitespace such as line
+// wrapping is ignored. It works with both byte and string returning Run
+// methods.
+func NewBase64DecodePostModifier(encoding Base64Encoding) *base64DecodePostModifier {
+	return &base64DecodePostModifier{encoding: encoding}
+}
+
+func (m *base64DecodePostModifier) processBytes(content []byte) ([]byte, error) {
+	encoded := bytes.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, content)
+	decoded := make([]byte, m.encoding.encoding().DecodedLen(len(encoded)))
+	n, err := m.encoding.encoding().Decode(decoded, encoded)
+	if err != nil {
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) {
+			return content, fmt.Errorf("invalid base64 output at byte %d of the encoded data", int64(corrupt))
+		}
+		return content, fmt.Errorf("invalid base64 output: %w", err)
+	}
+	return decoded[:n], nil
+}
+
+func (m *base64DecodePostModifier) process(content string) (string, error) {
+	decoded, err := m.processBytes([]byte(content))
+	if err != nil {
+		return content, err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,88 @@
+package command_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func gzipped(t *testing.T, content string) string {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return compressed.String()
+}
+
+func TestGunzipPostModifier(t *testing.T) {
+	out, err := outputCommand(gzipped(t, "payload")).RunStdout(command.NewGunzipPostModifier())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != "payload" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if _, err := outputCommand("plain").RunStdout(command.NewGunzipPostModifier()); err == nil {
+		t.Error("expected output that is not gzip to fail")
+	}
+}
+
+func TestBytesPostModifierFunc(t *testing.T) {
+	double := command.BytesPostModifierFunc(func(content []byte) ([]byte, error) {
+		return bytes.Repeat(content, 2), nil
+	})
+	out, err := outputCommand(gzipped(t, "ab")).RunStdout(command.NewGunzipPostModifier(), double)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != "abab" {
+		t.Errorf("expected the modifiers to apply in order, got %q", out)
+	}
+	failure := errors.New("rejected")
+	reject := command.BytesPostModifierFunc(func(content []byte) ([]byte, error) {
+		return content, failure
+	})
+	if _, err := outputCommand("ab").RunStdout(reject); !errors.Is(err, failure) {
+		t.Errorf("expected the error of the function, got %v", err)
+	}
+}
+
+func TestBase64DecodePostModifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		encoding command.Base64Encoding
+	}{
+		{name: "std", output: "aGk/Pz8+\n", encoding: command.Base64Std},
+		{name: "wrapped", output: "aGk/\nPz8+\n", encoding: command.Base64Std},
+		{name: "url", output: "aGk_Pz8-", encoding: command.Base64URL},
+		{name: "raw std", output: "aGk/Pz8+", encoding: command.Base64RawStd},
+		{name: "raw url", output: "aGk_Pz8-", encoding: command.Base64RawURL},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := outputCommand(test.output).RunStdout(command.NewBase64DecodePostModifier(test.encoding))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(out) != "hi???>" {
+				t.Errorf("unexpected output %q", out)
+			}
+		})
+	}
+	str, err := outputCommand("aGk=").RunStdoutStr(command.NewBase64DecodePostModifier(command.Base64Std))
+	if err != nil || str != "hi" {
+		t.Errorf("expected the string variant to decode, got %q and %v", str, err)
+	}
+	if _, err := outputCommand("a*b").RunStdout(command.NewBase64DecodePostModifier(command.Base64Std)); err == nil {
+		t.Error("expected invalid base64 to fail")
+	}
+}
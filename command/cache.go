@@ -0,0 +1,144 @@
+package command
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	cmd     string
+	args    []string
+	chunks  []OutputChunk
+	expires time.Time
+}
+
+type CachingFactory struct {
+	inner   CommandFactory
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// NewCachingFactory returns a factory replaying the output of successful
+// executions of inner commands with the same command line, directory and
+// environment for ttl, a ttl of zero or less never expires. Entries expire
+// with the clock of the command looking them up. Executions fed with stdin or
+// started with Start are never cached.
+func NewCachingFactory(inner CommandFactory, ttl time.Duration) *CachingFactory {
+	return &CachingFactory{inner: inner, ttl: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// WithNoCache opts a command out of the caching factory that created it.
+func WithNoCache() Option {
+	return func(r *commandRequest) {
+		r.noCache = true
+	}
+}
+
+func (f *CachingFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(f.cached))
+}
+
+// Invalidate drops the cached executions of the given command line, whatever
+// their directory or environment.
+func (f *CachingFactory) Invalidate(cmd string, args ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, entry := range f.entries {
+		if entry.cmd == cmd && slices.Equal(entry.args, args) {
+			delete(f.entries, key)
+		}
+	}
+}
+
+func (f *CachingFactory) Purge() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.entries)
+}
+
+func (f *CachingFactory) cached(next execFunc) execFunc {
+	return func(req *commandRequest, s *streams) error {
+		if req.noCache || s.stdin != nil || s.onStart != nil {
+			return next(req, s)
+		}
+		key := cacheKey(req)
+		clock := req.clockOrDefault()
+		if entry := f.lookup(key, clock.Now()); entry != nil {
+			return replayChunks(entry.chunks, s)
+		}
+		stdout, stderr := s.stdout, s.stderr
+		defer func() { s.stdout, s.stderr = stdout, stderr }()
+		recorder := &chunkRecorder{clock: clock}
+		s.tee(&chunkWriter{recorder: recorder, stream: StreamStdout}, &chunkWriter{recorder: recorder, stream: StreamStderr})
+		if err := next(req, s); err != nil {
+			return err
+		}
+		entry := &cacheEntry{cmd: req.cmd, args: slices.Clone(req.args), chunks: recorder.chunks}
+		if f.ttl > 0 {
+			entry.expires = clock.Now().Add(f.ttl)
+		}
+		f.mu.Lock()
+		f.entries[key] = entry
+		f.mu.Unlock()
+		return nil
+	}
+}
+
+func (f *CachingFactory) lookup(key string, now time.Time) *cacheEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.entries[key]
+	if !ok {
+		return nil
+	}
+	if !entry.expires.IsZero() && now.After(entry.expires) {
+		delete(f.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func replayChunks(chunks []OutputChunk, s *streams) error {
+	for _, chunk := range chunks {
+		writer := s.stdout
+		if chunk.Stream == StreamStderr {
+			writer = s.stderr
+		}
+		if writer == nil {
+			continue
+		}
+		if _, err := writer.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cacheKey(req *commandRequest) string {
+	var key strings.Builder
+	key.WriteString(req.cmd)
+	for _, arg := range req.args {
+		key.WriteString("\x00" + arg)
+	}
+	key.WriteString("\x01" + req.dir)
+	if req.noEnvInherit {
+		key.WriteString("\x01-i")
+	}
+	for _, env := range []map[string]string{req.fileEnv, req.env} {
+		keys := make([]string, 0, len(env))
+		for name := range env {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		key.WriteString("\x01")
+		for _, name := range keys {
+			key.WriteString("\x00" + name + "=" + env[name])
+		}
+	}
+	return key.String()
+}
@@ -0,0 +1,135 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestCachingFactoryExpiresWithClock(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	log := newRunLog(t)
+	inner := countingFactory{log}
+	factory := command.NewCachingFactory(inner, time.Minute)
+	run := func() {
+		t.Helper()
+		if err := factory.Command(context.Background(), "true").With(command.WithClock(clock)).Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	run()
+	clock.Advance(59 * time.Second)
+	run()
+	if log.runs() != 1 {
+		t.Errorf("expected the cached execution to be replayed, ran %d times", log.runs())
+	}
+	clock.Advance(2 * time.Second)
+	run()
+	if log.runs() != 2 {
+		t.Errorf("expected the expired entry to run again, ran %d times", log.runs())
+	}
+}
+
+// countingFactory runs its commands as a script echoing their arguments and run
+// number to stdout and stderr, failing the ones named fail. The decorating
+// factories still see the original commands.
+type countingFactory struct {
+	log runLog
+}
+
+func (f countingFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	script := f.log.script(`printf 'out %s %d' "$*" "$RUN"; printf 'err %d' "$RUN" >&2; [ "$0" != fail ]`)
+	return command.NewExecCmdFactory().Command(ctx, cmd, args...).With(command.WithCmdCustomizer(func(c *exec.Cmd) {
+		c.Path, c.Err = "/bin/sh", nil
+		c.Args = append([]string{"sh", "-c", script, cmd, string(f.log)}, args...)
+	}))
+}
+
+// clockedFactory sets clock on the commands of inner.
+type clockedFactory struct {
+	inner command.CommandFactory
+	clock command.Clock
+}
+
+func (f clockedFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(command.WithClock(f.clock))
+}
+
+func TestCachingFactoryReplaysOutput(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewCachingFactory(countingFactory{log}, 0)
+	for range 2 {
+		var stdout, stderr bytes.Buffer
+		if err := factory.Command(context.Background(), "list", "a").RunToWriter(&stdout, &stderr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stdout.String() != "out a 1" || stderr.String() != "err 1" {
+			t.Errorf("expected the first output to be replayed, got %q and %q", stdout.String(), stderr.String())
+		}
+	}
+	if log.runs() != 1 {
+		t.Errorf("expected a single execution, ran %d times", log.runs())
+	}
+}
+
+func TestCachingFactoryKeys(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewCachingFactory(countingFactory{log}, 0)
+	for _, r := range []command.Runnable{
+		factory.Command(context.Background(), "list", "a"),
+		factory.Command(context.Background(), "list", "b"),
+		factory.Command(context.Background(), "list", "a").With(command.WithDir(t.TempDir())),
+		factory.Command(context.Background(), "list", "a").With(command.WithEnv(map[string]string{"A": "1"})),
+		factory.Command(context.Background(), "list", "a").With(command.WithNoCache()),
+	} {
+		if err := r.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if log.runs() != 5 {
+		t.Errorf("expected every command to run, ran %d times", log.runs())
+	}
+}
+
+func TestCachingFactorySkipsFailuresAndInput(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewCachingFactory(countingFactory{log}, 0)
+	for range 2 {
+		_ = factory.Command(context.Background(), "fail").Run()
+		if _, err := factory.Command(context.Background(), "list").RunWithInputStr("input"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if log.runs() != 4 {
+		t.Errorf("expected failed executions and executions with input to run again, ran %d times", log.runs())
+	}
+}
+
+func TestCachingFactoryInvalidate(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewCachingFactory(countingFactory{log}, 0)
+	run := func(args ...string) {
+		t.Helper()
+		if err := factory.Command(context.Background(), "list", args...).Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	run("a")
+	run("b")
+	factory.Invalidate("list", "a")
+	run("a")
+	run("b")
+	if log.runs() != 3 {
+		t.Errorf("expected only the invalidated command to run again, ran %d times", log.runs())
+	}
+	factory.Purge()
+	run("b")
+	if log.runs() != 4 {
+		t.Errorf("expected the purged command to run again, ran %d times", log.runs())
+	}
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+const maxErrorStderrBytes = 32 << 10
+
+// tailMinRead is the space a tailBuffer reading its input leaves for each read.
+const tailMinRead = 4 << 10
+
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// captures are the buffers owned by the Run method itself, which are reset
+	// when an execution is repeated.
+	captures []captureBuffer
+	onStart  func(process *os.Process, signal func(os.Signal) error)
+	// onExit is called once the process started by onStart was waited for.
+	onExit func()
+	// truncated reports that a capture buffer dropped output over its limit.
+	truncated bool
+}
+
+type captureBuffer interface {
+	io.Writer
+	Reset()
+}
+
+func captureStreams(stdin io.Reader, stdout captureBuffer, stderr captureBuffer) *streams {
+	s := &streams{stdin: stdin}
+	if stdout != nil {
+		s.stdout = stdout
+		s.captures = append(s.captures, stdout)
+	}
+	if stderr != nil {
+		s.stderr = stderr
+		s.captures = append(s.captures, stderr)
+	}
+	return s
+}
+
+// addCapture makes capture reset when the execution is repeated, it is kept
+// for the whole execution as a repetition can be decided by any middleware.
+func (s *streams) addCapture(capture captureBuffer) {
+	if !slices.Contains(s.captures, capture) {
+		s.captures = append(s.captures, capture)
+	}
+}
+
+func (s *streams) rewind() bool {
+	if s.stdin != nil {
+		seeker, ok := s.stdin.(io.Seeker)
+		if !ok {
+			return false
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+	}
+	for _, capture := range s.captures {
+		capture.Reset()
+	}
+	return true
+}
+
+type tailBuffer struct {
+	limit int
+	data  []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= t.limit {
+		t.data = append(t.data[:0], p[len(p)-t.limit:]...)
+		return n, nil
+	}
+	if overflow := len(t.data) + len(p) - t.limit; overflow > 0 {
+		t.data = append(t.data[:0], t.data[overflow:]...)
+	}
+	t.data = append(t.data, p...)
+	return n, nil
+}
+
+// ReadFrom reads into the buffer itself, exec copying the stderr pipe with
+// io.Copy which would otherwise allocate a copy buffer for each execution.
+// The tail is only cut when the buffer runs out of room, so it is shifted
+// about once for every limit bytes read rather than on each read.
+func (t *tailBuffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	defer t.trim()
+	for {
+		if cap(t.data)-len(t.data) < tailMinRead {
+			t.trim()
+			t.data = slices.Grow(t.data, t.limit)
+		}
+		n, err := r.Read(t.data[len(t.data):cap(t.data)])
+		t.data = t.data[:len(t.data)+n]
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func (t *tailBuffer) trim() {
+	if len(t.data) > t.limit {
+		t.data = t.data[:copy(t.data, t.data[len(t.data)-t.limit:])]
+	}
+}
+
+func (t *tailBuffer) Bytes() []byte {
+	return t.data
+}
+
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a != nil && a == b
+}
+
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buffer.Reset()
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buffer.Bytes()...)
+}
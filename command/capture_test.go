@@ -0,0 +1,31 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestCommandErrorKeepsStderrTail(t *testing.T) {
+	// Lines of varying length written in many small writes, so the tail is
+	// cut in the middle of the reads of the stderr pipe.
+	script := `i=0; while [ $i -lt 20000 ]; do echo "line $i"; i=$((i+1)); done >&2; exit 3`
+	err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", script).Run()
+
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected a CommandError, got %v", err)
+	}
+	var expected bytes.Buffer
+	for i := range 20000 {
+		fmt.Fprintf(&expected, "line %d\n", i)
+	}
+	tail := expected.Bytes()[expected.Len()-32<<10:]
+	if !bytes.Equal(cmdErr.Stderr(), tail) {
+		t.Errorf("stderr of %d bytes is not the last %d bytes written", len(cmdErr.Stderr()), len(tail))
+	}
+}
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var ErrInjectedFailure = errors.New("injected failure")
+
+// ChaosConfig sets the faults a chaos factory injects, rates being
+// probabilities between 0 and 1 drawn for each execution.
+type ChaosConfig struct {
+	// FailureRate is how often executions fail with ErrInjectedFailure
+	// without running the command.
+	FailureRate float64
+	// ExtraLatency is the upper bound of a random delay added before the
+	// executions.
+	ExtraLatency time.Duration
+	// CorruptOutput is how often executions have a random byte of each write
+	// to stdout replaced.
+	CorruptOutput float64
+	// SeededRand makes the injected faults reproducible, a randomly seeded
+	// generator is used when nil.
+	SeededRand *rand.Rand
+}
+
+type chaosFactory struct {
+	inner  CommandFactory
+	config ChaosConfig
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewChaosFactory injects faults in the executions of the commands of inner,
+// to test how the code using them copes with failing, slow or misbehaving
+// commands.
+func NewChaosFactory(inner CommandFactory, config ChaosConfig) CommandFactory {
+	random := config.SeededRand
+	if random == nil {
+		random = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return &chaosFactory{inner: inner, config: config, rand: random}
+}
+
+func (f *chaosFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(f.inject))
+}
+
+// chaosDraw holds the faults of an execution, drawn together so a seeded
+// generator gives the same faults for the same sequence of executions.
+type chaosDraw struct {
+	fail    bool
+	delay   time.Duration
+	corrupt bool
+}
+
+func (f *chaosFactory) draw() chaosDraw {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	draw := chaosDraw{
+		fail:    f.rand.Float64() < f.config.FailureRate,
+		corrupt: f.rand.Float64() < f.config.CorruptOutput,
+	}
+	if f.config.ExtraLatency > 0 {
+		draw.delay = time.Duration(f.rand.Int64N(int64(f.config.ExtraLatency) + 1))
+	}
+	return draw
+}
+
+func (f *chaosFactory) intN(n int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rand.IntN(n)
+}
+
+func (f *chaosFactory) inject(next execFunc) execFunc {
+	return func(req *commandRequest, s *streams) error {
+		draw := f.draw()
+		if draw.delay > 0 {
+			timer := req.clockOrDefault().NewTimer(draw.delay)
+			select {
+			case <-req.ctx.Done():
+				timer.Stop()
+				return newCommandError(req, &canceledError{cause: context.Cause(req.ctx), err: req.ctx.Err()}, nil, 0)
+			case <-timer.C():
+			}
+		}
+		if draw.fail {
+			return newCommandError(req, ErrInjectedFailure, nil, 0)
+		}
+		if draw.corrupt && s.stdout != nil {
+			stdout, stderr := s.stdout, s.stderr
+			defer func() { s.stdout, s.stderr = stdout, stderr }()
+			if sameWriter(stdout, stderr) {
+				shared := &lockedWriter{writer: stdout}
+				s.stdout, s.stderr = shared, shared
+			}
+			s.stdout = &corruptingWriter{writer: s.stdout, factory: f}
+		}
+		return next(req, s)
+	}
+}
+
+type corruptingWriter struct {
+	writer  io.Writer
+	factory *chaosFactory
+}
+
+func (w *corruptingWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return w.writer.Write(p)
+	}
+	corrupted := append([]byte(nil), p...)
+	corrupted[w.factory.intN(len(corrupted))] ^= 0xff
+	return w.writer.Write(corrupted)
+}
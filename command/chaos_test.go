@@ -0,0 +1,89 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestChaosFactoryFailures(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewChaosFactory(countingFactory{log}, command.ChaosConfig{FailureRate: 1})
+	err := factory.Command(context.Background(), "job").Run()
+	var cmdErr *command.CommandError
+	if !errors.Is(err, command.ErrInjectedFailure) || !errors.As(err, &cmdErr) {
+		t.Errorf("expected a CommandError wrapping ErrInjectedFailure, got %v", err)
+	}
+	if log.runs() != 0 {
+		t.Errorf("expected the command not to run, ran %d times", log.runs())
+	}
+}
+
+func TestChaosFactoryIsReproducible(t *testing.T) {
+	outcomes := func() []bool {
+		log := newRunLog(t)
+		factory := command.NewChaosFactory(countingFactory{log}, command.ChaosConfig{
+			FailureRate: 0.5,
+			SeededRand:  rand.New(rand.NewPCG(1, 2)),
+		})
+		var failed []bool
+		for range 20 {
+			failed = append(failed, factory.Command(context.Background(), "job").Run() != nil)
+		}
+		return failed
+	}
+	first := outcomes()
+	if !slices.Contains(first, true) || !slices.Contains(first, false) {
+		t.Errorf("expected some executions to fail, got %v", first)
+	}
+	if second := outcomes(); !slices.Equal(first, second) {
+		t.Errorf("expected the same seed to give the same faults, got %v and %v", first, second)
+	}
+}
+
+func TestChaosFactoryCorruptsOutput(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewChaosFactory(countingFactory{log}, command.ChaosConfig{CorruptOutput: 1})
+	out, err := factory.Command(context.Background(), "job").RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "out  1"
+	changed := 0
+	for index := range min(len(out), len(expected)) {
+		if out[index] != expected[index] {
+			changed++
+		}
+	}
+	if len(out) != len(expected) || changed != 1 {
+		t.Errorf("expected a single corrupted byte, got %q", out)
+	}
+}
+
+func TestChaosFactoryLatency(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	log := newRunLog(t)
+	factory := command.NewChaosFactory(countingFactory{log}, command.ChaosConfig{ExtraLatency: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go func() { done <- factory.Command(context.Background(), "job").With(command.WithClock(clock)).Run() }()
+	go func() { done <- factory.Command(ctx, "job").With(command.WithClock(clock)).Run() }()
+	clock.WaitForTimers(2)
+	cancel()
+	if err := <-done; !command.IsCanceled(err) {
+		t.Errorf("expected the canceled execution to stop waiting, got %v", err)
+	}
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if log.runs() != 1 {
+		t.Errorf("expected only the delayed command to run, ran %d times", log.runs())
+	}
+}
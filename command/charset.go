@@ -0,0 +1,42 @@
+package command
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+type charsetPostModifier struct {
+	name     string
+	encoding encoding.Encoding
+	err      error
+}
+
+// NewCharsetPostModifier converts output in the named encoding, such as
+// latin1, windows-1252, shift_jis or utf-16le, to UTF-8. Names follow the
+// WHATWG Encoding Standard, which treats latin1 as windows-1252. It works with
+// both byte and string returning Run methods.
+func NewCharsetPostModifier(name string) *charsetPostModifier {
+	enc, err := htmlindex.Get(name)
+	return &charsetPostModifier{name: name, encoding: enc, err: err}
+}
+
+func (m *charsetPostModifier) processBytes(content []byte) ([]byte, error) {
+	if m.err != nil {
+		return content, fmt.Errorf("unsupported charset %q: %w", m.name, m.err)
+	}
+	decoded, err := m.encoding.NewDecoder().Bytes(content)
+	if err != nil {
+		return content, fmt.Errorf("failed to convert output from %s: %w", m.name, err)
+	}
+	return decoded, nil
+}
+
+func (m *charsetPostModifier) process(content string) (string, error) {
+	decoded, err := m.processBytes([]byte(content))
+	if err != nil {
+		return content, err
+	}
+	return string(decoded), nil
+}
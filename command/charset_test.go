@@ -0,0 +1,36 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestCharsetPostModifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		output  string
+	}{
+		{name: "latin1", charset: "latin1", output: "caf\xe9 \x80"},
+		{name: "utf-16le", charset: "utf-16le", output: "c\x00a\x00f\x00\xe9\x00 \x00\xac\x20"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := outputCommand(test.output).RunStdout(command.NewCharsetPostModifier(test.charset))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(out) != "café €" {
+				t.Errorf("unexpected output %q", out)
+			}
+			str, err := outputCommand(test.output).RunStdoutStr(command.NewCharsetPostModifier(test.charset))
+			if err != nil || str != "café €" {
+				t.Errorf("expected the string variant to convert, got %q and %v", str, err)
+			}
+		})
+	}
+	if _, err := outputCommand("a").RunStdout(command.NewCharsetPostModifier("no-such-charset")); err == nil {
+		t.Error("expected an unknown charset to fail")
+	}
+}
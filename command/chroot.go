@@ -0,0 +1,9 @@
+package command
+
+// WithChroot runs the command with dir as its root directory. The executable,
+// WithDir and relative lookups are resolved inside the new root.
+func WithChroot(dir string) Option {
+	return func(r *commandRequest) {
+		r.chroot = dir
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package command
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func applyChroot(*exec.Cmd, string, string) error {
+	return errors.New("changing the root directory is not supported on this platform")
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithChrootLooksUpInsideTheRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the root directory requires root")
+	}
+	err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "true").
+		With(command.WithChroot(t.TempDir())).Run()
+	if !errors.Is(err, command.ErrExecutableNotFound) {
+		t.Errorf("expected sh not to be found in an empty root, got %v", err)
+	}
+}
+
+func TestWithChrootRequiresADirectory(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the root directory requires root")
+	}
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := command.NewExecCmdFactory().Command(context.Background(), "true").With(command.WithChroot(file)).Run()
+	if err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("expected a not a directory error, got %v", err)
+	}
+}
+
+func TestWithChrootRequiresRoot(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("running as root")
+	}
+	err := command.NewExecCmdFactory().Command(context.Background(), "true").With(command.WithChroot(t.TempDir())).Run()
+	if !errors.Is(err, command.ErrPrivilegeRequired) {
+		t.Errorf("expected a privilege error, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var chrootPath = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+
+func applyChroot(cmd *exec.Cmd, root, name string) error {
+	if euid := os.Geteuid(); euid != 0 {
+		return fmt.Errorf("%w: running as uid %d, changing the root directory requires root", ErrPrivilegeRequired, euid)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return fmt.Errorf("failed to use %q as root directory: %w", root, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("failed to use %q as root directory: not a directory", root)
+	}
+	executable, err := lookPathIn(root, name)
+	if err != nil {
+		return err
+	}
+	// The host lookup done by exec is meaningless inside the new root.
+	cmd.Path = executable
+	cmd.Err = nil
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = root
+	return nil
+}
+
+func lookPathIn(root, name string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	for _, dir := range chrootPath {
+		candidate := path.Join(dir, name)
+		if info, err := os.Stat(filepath.Join(root, candidate)); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q in root directory %q", ErrExecutableNotFound, name, root)
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"sync"
+	"time"
+)
+
+type OutputStream int
+
+const (
+	StreamStdout OutputStream = iota
+	StreamStderr
+)
+
+func (s OutputStream) String() string {
+	if s == StreamStderr {
+		return "stderr"
+	}
+	return "stdout"
+}
+
+type OutputChunk struct {
+	Stream OutputStream
+	Time   time.Time
+	Data   []byte
+}
+
+type chunkRecorder struct {
+	clock  Clock
+	mu     sync.Mutex
+	chunks []OutputChunk
+}
+
+func (r *chunkRecorder) record(stream OutputStream, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks = append(r.chunks, OutputChunk{Stream: stream, Time: r.clock.Now(), Data: append([]byte(nil), p...)})
+}
+
+func (r *chunkRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks = nil
+}
+
+type chunkWriter struct {
+	recorder *chunkRecorder
+	stream   OutputStream
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.recorder.record(w.stream, p)
+	return len(p), nil
+}
+
+func (w *chunkWriter) Reset() {
+	w.recorder.reset()
+}
+
+// RunChunks captures stdout and stderr as the chunks read from each stream.
+// The two streams are read concurrently, so ordering between them is the one
+// observed by the reader and can differ slightly from the write order.
+func (e *execCommand) RunChunks() ([]OutputChunk, error) {
+	recorder := &chunkRecorder{clock: e.clockOrDefault()}
+	err := e.run(captureStreams(nil,
+		&chunkWriter{recorder: recorder, stream: StreamStdout},
+		&chunkWriter{recorder: recorder, stream: StreamStderr}))
+	return recorder.chunks, err
+}
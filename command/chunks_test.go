@@ -0,0 +1,30 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestRunChunks(t *testing.T) {
+	r := streamsCommand("out 1", "!err", "out 2")
+	chunks, err := r.RunChunks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		stream command.OutputStream
+		data   string
+	}{{command.StreamStdout, "out 1"}, {command.StreamStderr, "err"}, {command.StreamStdout, "out 2"}}
+	if len(chunks) != len(expected) {
+		t.Fatalf("expected %d chunks, got %+v", len(expected), chunks)
+	}
+	for index, chunk := range chunks {
+		if chunk.Stream != expected[index].stream || string(chunk.Data) != expected[index].data {
+			t.Errorf("chunk %d: expected %s %q, got %s %q", index, expected[index].stream, expected[index].data, chunk.Stream, chunk.Data)
+		}
+		if chunk.Time.IsZero() || index > 0 && chunk.Time.Before(chunks[index-1].Time) {
+			t.Errorf("chunk %d: unexpected time %s", index, chunk.Time)
+		}
+	}
+}
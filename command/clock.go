@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time of the package, used for timeouts, backoffs and
+// measured durations. Tests can replace it with a fake, like the one of the
+// commandtest package, to control time instead of waiting.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f once d elapsed, the returned timer having a nil
+	// channel.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock returns the Clock used by default, backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// WithClock makes the command measure and wait time with clock, it is the
+// only way to replace the clock of the package: set it on the factory with
+// WithDefaultOptions to cover the wrapping factories, Poll and sessions too.
+// Only the wait of os/exec for the process after the grace period of
+// WithGracefulStop still uses the system clock.
+func WithClock(clock Clock) Option {
+	return func(r *commandRequest) {
+		r.clock = clock
+	}
+}
+
+func (r *commandRequest) clockOrDefault() Clock {
+	if r.clock == nil {
+		return systemClock{}
+	}
+	return r.clock
+}
+
+// clockOf returns the clock r runs with.
+func clockOf(r Runnable) Clock {
+	var clock Clock = systemClock{}
+	r.With(func(req *commandRequest) {
+		clock = req.clockOrDefault()
+	})
+	return clock
+}
+
+// withTimeoutCause is context.WithTimeoutCause driven by clock.
+func withTimeoutCause(ctx context.Context, clock Clock, timeout time.Duration, cause error) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(systemClock); ok {
+		return context.WithTimeoutCause(ctx, timeout, cause)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := clock.AfterFunc(timeout, func() { cancel(cause) })
+	return ctx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
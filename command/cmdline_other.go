@@ -0,0 +1,7 @@
+//go:build !windows
+
+package command
+
+import "os/exec"
+
+func setRawCmdLine(*exec.Cmd, string) {}
@@ -0,0 +1,13 @@
+package command
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setRawCmdLine(cmd *exec.Cmd, line string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CmdLine = line
+}
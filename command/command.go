@@ -1,12 +1,22 @@
 package command
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"os"
 	"os/exec"
+	"slices"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 type PostModifierTrimOption int
@@ -21,15 +31,117 @@ type RunnablePostModifier interface {
 	process(content string) (string, error)
 }
 
+// PostModifierFunc adapts a function to a RunnablePostModifier.
+type PostModifierFunc func(content string) (string, error)
+
+func (f PostModifierFunc) process(content string) (string, error) {
+	return f(content)
+}
+
+type ModifierErrorPolicy int
+
+const (
+	// ModifierFailFast stops at the first failing modifier and returns no
+	// output.
+	ModifierFailFast ModifierErrorPolicy = iota
+	// ModifierReturnPartial stops at the first failing modifier and returns
+	// the output of the modifiers before it.
+	ModifierReturnPartial
+	// ModifierSkipAndContinue ignores failing modifiers, the chain goes on
+	// with the output they were given and all failures are returned.
+	ModifierSkipAndContinue
+)
+
+// WithModifierErrorPolicy sets how the Run methods handle failing modifiers.
+func WithModifierErrorPolicy(policy ModifierErrorPolicy) Option {
+	return func(r *commandRequest) {
+		r.modifierPolicy = policy
+	}
+}
+
+type ModifierError struct {
+	// Index is the position of the modifier in the chain.
+	Index    int
+	Modifier any
+	Err      error
+}
+
+func (e *ModifierError) Error() string {
+	return fmt.Sprintf("modifier %d (%T) failed: %v", e.Index, e.Modifier, e.Err)
+}
+
+func (e *ModifierError) Unwrap() error {
+	return e.Err
+}
+
+func applyChain[T any, M any](content T, modifiers []M, policy ModifierErrorPolicy, apply func(M, T) (T, error)) (T, error) {
+	result := content
+	var errs []error
+	for index, modifier := range modifiers {
+		procRes, procErr := apply(modifier, result)
+		if procErr == nil {
+			result = procRes
+			continue
+		}
+		modErr := &ModifierError{Index: index, Modifier: modifier, Err: procErr}
+		switch policy {
+		case ModifierSkipAndContinue:
+			errs = append(errs, modErr)
+		case ModifierReturnPartial:
+			return result, modErr
+		default:
+			var empty T
+			return empty, modErr
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func applyModifiers(content string, modifiers []RunnablePostModifier, policy ModifierErrorPolicy) (string, error) {
+	return applyChain(content, modifiers, policy, RunnablePostModifier.process)
+}
+
+// BytesPostModifier transforms the raw output of the byte returning Run
+// methods, such as decompressing it.
+type BytesPostModifier interface {
+	processBytes(content []byte) ([]byte, error)
+}
+
+type BytesPostModifierFunc func(content []byte) ([]byte, error)
+
+func (f BytesPostModifierFunc) processBytes(content []byte) ([]byte, error) {
+	return f(content)
+}
+
+func applyBytesModifiers(content []byte, modifiers []BytesPostModifier, policy ModifierErrorPolicy) ([]byte, error) {
+	return applyChain(content, modifiers, policy, BytesPostModifier.processBytes)
+}
+
 type trimPostModifier struct {
 	trimOpt  PostModifierTrimOption
 	trimChar string
 }
 
+// NewTrimPostModifier removes any of the characters in char, which is a
+// cutset and not a suffix or prefix, from the selected ends of the output.
 func NewTrimPostModifier(option PostModifierTrimOption, char string) *trimPostModifier {
 	return &trimPostModifier{trimOpt: option, trimChar: char}
 }
 
+func NewTrimCutsetPostModifier(option PostModifierTrimOption, cutset string) *trimPostModifier {
+	return NewTrimPostModifier(option, cutset)
+}
+
+// NewTrimSpacePostModifier removes leading and trailing whitespace.
+func NewTrimSpacePostModifier() *stringPostModifier {
+	return &stringPostModifier{transform: strings.TrimSpace}
+}
+
+// NewTrimNewlinePostModifier removes trailing line endings, both \n and \r\n.
+func NewTrimNewlinePostModifier() *trimPostModifier {
+	return NewTrimPostModifier(PostModifierTrimRight, "\r\n")
+}
+
 func (t *trimPostModifier) process(content string) (string, error) {
 
 	switch t.trimOpt {
@@ -44,80 +156,635 @@ func (t *trimPostModifier) process(content string) (string, error) {
 	}
 }
 
+// Runnable describes a command, every Run method or Start call executes a new
+// process. A Runnable is never modified once created, it can be kept and run
+// repeatedly, also from several goroutines at once.
+//
+// When the command fails, the Run methods returning bytes return the output
+// captured until then along with the error, without applying the
+// post-modifiers. The ones returning a string return an empty one, and the ones
+// parsing the output, like RunLines, return nil.
 type Runnable interface {
 	Run() error
-	RunStdout() ([]byte, error)
+	RunStdout(modifiers ...BytesPostModifier) ([]byte, error)
 	RunStdoutStr(modifiers ...RunnablePostModifier) (string, error)
-	RunCombined() ([]byte, error)
-	RunCombinedStr() (string, error)
+	RunLines(modifiers ...RunnablePostModifier) ([]string, error)
+	RunNullDelimited() ([]string, error)
+	RunStderr(modifiers ...BytesPostModifier) ([]byte, error)
+	RunStderrStr(modifiers ...RunnablePostModifier) (string, error)
+	RunCombined(modifiers ...BytesPostModifier) ([]byte, error)
+	RunCombinedStr(modifiers ...RunnablePostModifier) (string, error)
+
+	RunWithInput(input []byte) ([]byte, error)
+	RunCombinedWithInput(input []byte) ([]byte, error)
+	RunChunks() ([]OutputChunk, error)
+	RunWithInputStr(input string, modifiers ...RunnablePostModifier) (string, error)
 
 	RunToWriter(stdout io.Writer, stderr io.Writer) error
+	RunIO(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+	RunStream(onStdoutLine func(line string), onStderrLine func(line string)) error
+	RunScan(split bufio.SplitFunc, handle func(token []byte) error) error
+	Start() (Process, error)
+
+	Execute() (*Result, error)
+
+	// With returns a copy of the Runnable with opts applied, leaving the
+	// original untouched.
+	With(opts ...Option) Runnable
+	Err() error
+}
+
+type Option func(*commandRequest)
+
+func WithEnv(env map[string]string) Option {
+	return func(r *commandRequest) {
+		if r.env == nil {
+			r.env = make(map[string]string, len(env))
+		}
+		for key, value := range env {
+			r.env[key] = value
+		}
+	}
+}
+
+// WithArgs appends args to the arguments of the command.
+func WithArgs(args ...string) Option {
+	return func(r *commandRequest) {
+		r.args = append(r.args, args...)
+	}
+}
+
+func WithEnvInherit(inherit bool) Option {
+	return func(r *commandRequest) {
+		r.noEnvInherit = !inherit
+	}
+}
+
+func WithDir(dir string) Option {
+	return func(r *commandRequest) {
+		r.dir = dir
+	}
+}
+
+// ErrStdinConsumed is returned when running again a command whose stdin
+// reader was already given to a previous execution.
+var ErrStdinConsumed = errors.New("stdin reader already consumed")
+
+// WithStdinReader streams stdin from reader for the Run methods that do not
+// take an input of their own. The reader is consumed by the first execution,
+// the following ones, including those of the Runnables derived with With,
+// fail with ErrStdinConsumed.
+func WithStdinReader(reader io.Reader) Option {
+	return func(r *commandRequest) {
+		r.stdin = reader
+		r.stdinClaimed = new(atomic.Bool)
+	}
+}
+
+// WithGracefulStop sends sig instead of killing the process when the command
+// context is done, the process is killed if it is still running after
+// gracePeriod. The grace period must be positive, the command fails without
+// running otherwise.
+func WithGracefulStop(sig os.Signal, gracePeriod time.Duration) Option {
+	return func(r *commandRequest) {
+		if gracePeriod <= 0 {
+			r.err = fmt.Errorf("invalid graceful stop grace period %s, it must be positive", gracePeriod)
+			return
+		}
+		r.stopSignal = sig
+		r.stopGracePeriod = gracePeriod
+	}
+}
+
+// WithProcAttr customizes the process attributes after the package has set its
+// own ones.
+func WithProcAttr(customize func(attr *syscall.SysProcAttr)) Option {
+	return func(r *commandRequest) {
+		r.procAttrCustomizers = append(r.procAttrCustomizers, customize)
+	}
+}
+
+// WithCmdCustomizer gives access to the exec.Cmd right before it is started.
+// Streams are already wired at that point and replacing them breaks output
+// capture.
+func WithCmdCustomizer(customize func(cmd *exec.Cmd)) Option {
+	return func(r *commandRequest) {
+		r.cmdCustomizers = append(r.cmdCustomizers, customize)
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *commandRequest) {
+		r.timeout = timeout
+	}
 }
 
 type commandRequest struct {
-	ctx  context.Context
-	cmd  string
-	args []string
+	ctx                 context.Context
+	cmd                 string
+	args                []string
+	env                 map[string]string
+	fileEnv             map[string]string
+	secretArgs          []string
+	userArgs            []string
+	secretEnv           []string
+	noEnvInherit        bool
+	pathPrepend         []string
+	dir                 string
+	rawCmdLine          string
+	stdin               io.Reader
+	stdinClaimed        *atomic.Bool
+	teeStdout           io.Writer
+	teeStderr           io.Writer
+	debugEcho           io.Writer
+	timeout             time.Duration
+	clock               Clock
+	idleTimeout         time.Duration
+	maxLineSize         int
+	maxOutputBytes      int
+	noCache             bool
+	modifierPolicy      ModifierErrorPolicy
+	richErrors          bool
+	errorStderrLines    int
+	logAttrs            []slog.Attr
+	logGroup            string
+	outputLimitPolicy   OutputLimitPolicy
+	stopSignal          os.Signal
+	stopGracePeriod     time.Duration
+	processGroup        bool
+	pty                 *ptySize
+	credential          *credential
+	chroot              string
+	nice                *int
+	ioPriority          *ioPriority
+	rlimits             []RLimit
+	cgroup              *CgroupLimits
+	procAttrCustomizers []func(*syscall.SysProcAttr)
+	cmdCustomizers      []func(*exec.Cmd)
+	executor            execFunc
+	middlewares         []middleware
+	err                 error
+}
+
+func (r *commandRequest) clone() commandRequest {
+	cloned := *r
+	cloned.args = append([]string(nil), r.args...)
+	cloned.middlewares = append([]middleware(nil), r.middlewares...)
+	cloned.rlimits = slices.Clone(r.rlimits)
+	cloned.logAttrs = slices.Clone(r.logAttrs)
+	cloned.secretArgs = slices.Clone(r.secretArgs)
+	cloned.userArgs = slices.Clone(r.userArgs)
+	cloned.secretEnv = slices.Clone(r.secretEnv)
+	cloned.pathPrepend = slices.Clone(r.pathPrepend)
+	cloned.procAttrCustomizers = slices.Clone(r.procAttrCustomizers)
+	cloned.cmdCustomizers = slices.Clone(r.cmdCustomizers)
+	cloned.env = maps.Clone(r.env)
+	cloned.fileEnv = maps.Clone(r.fileEnv)
+	return cloned
+}
+
+func (r *commandRequest) context() (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(r.ctx)
+	}
+	return withTimeoutCause(r.ctx, r.clockOrDefault(), r.timeout, errCommandTimeout)
+}
+
+func (r *commandRequest) command(ctx context.Context) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, r.cmd, r.args...)
+	searchPath, pathChanged := r.searchPath()
+	if pathChanged {
+		// exec resolves the executable with the PATH of the current process.
+		if path, err := r.lookPathIn(searchPath); err != nil {
+			cmd.Err = err
+		} else {
+			cmd.Path, cmd.Err = path, nil
+		}
+	}
+	cmd.Env = r.environ(searchPath, pathChanged)
+	cmd.Dir = r.dir
+	if r.rawCmdLine != "" {
+		setRawCmdLine(cmd, r.rawCmdLine)
+	}
+	if r.stopSignal != nil {
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(r.stopSignal)
+		}
+		cmd.WaitDelay = r.stopGracePeriod
+	}
+	if r.chroot != "" {
+		if err := applyChroot(cmd, r.chroot, r.cmd); err != nil {
+			return nil, err
+		}
+	}
+	if r.credential != nil {
+		if err := applyCredential(cmd, r.credential); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+func (r *commandRequest) started(cmd *exec.Cmd, group *processGroup) error {
+	if group != nil {
+		if err := group.attach(); err != nil {
+			return err
+		}
+	}
+	if err := applyRLimits(r, cmd.Process.Pid); err != nil {
+		return err
+	}
+	return applyPriority(r, cmd.Process.Pid)
+}
+
+func (r *commandRequest) customize(cmd *exec.Cmd) {
+	for _, customize := range r.procAttrCustomizers {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		customize(cmd.SysProcAttr)
+	}
+	for _, customize := range r.cmdCustomizers {
+		customize(cmd)
+	}
+}
+
+func (r *commandRequest) environ(path string, pathChanged bool) []string {
+	if len(r.env) == 0 && len(r.fileEnv) == 0 && !pathChanged && !r.noEnvInherit {
+		return nil
+	}
+	var environ []string
+	if !r.noEnvInherit {
+		environ = os.Environ()
+	}
+	// Sized once for everything appended below, the sources being sorted
+	// through a single slice of keys.
+	environ = slices.Grow(environ, len(r.fileEnv)+len(r.env)+1)
+	keys := make([]string, 0, max(len(r.fileEnv), len(r.env)))
+	// exec keeps the last value of duplicated keys, so later sources win.
+	for _, env := range [...]map[string]string{r.fileEnv, r.env} {
+		keys = keys[:0]
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			environ = append(environ, key+"="+env[key])
+		}
+	}
+	if pathChanged {
+		environ = append(environ, "PATH="+path)
+	}
+	if environ == nil {
+		environ = []string{}
+	}
+	return environ
+}
+
+type execFunc func(req *commandRequest, s *streams) error
+
+type middleware func(next execFunc) execFunc
+
+func withMiddleware(mw middleware) Option {
+	return func(r *commandRequest) {
+		r.middlewares = append(r.middlewares, mw)
+	}
+}
+
+func runProcess(req *commandRequest, s *streams) (err error) {
+	ctx, cancel := req.context()
+	defer cancel()
+	clock := req.clockOrDefault()
+	var idle *idleMonitor
+	if req.idleTimeout > 0 {
+		ctx, idle = newIdleMonitor(ctx, req.idleTimeout, clock)
+		defer idle.stop()
+	}
+	req.echo()
+	cmd, err := req.command(ctx)
+	if err != nil {
+		return newCommandError(req, err, nil, 0)
+	}
+	cmd.Stdin = s.stdin
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+
+	// When both streams share a writer exec uses a single descriptor for them,
+	// so stderr cannot be told apart and is not kept for the error.
+	stderrTail := getTail()
+	defer putTail(stderrTail)
+	if s.stderr == nil {
+		cmd.Stderr = stderrTail
+	} else if !sameWriter(s.stdout, s.stderr) {
+		cmd.Stderr = io.MultiWriter(s.stderr, stderrTail)
+	}
+	if idle != nil {
+		combined := sameWriter(cmd.Stdout, cmd.Stderr)
+		cmd.Stdout = idle.watch(cmd.Stdout)
+		if combined {
+			cmd.Stderr = cmd.Stdout
+		} else {
+			cmd.Stderr = idle.watch(cmd.Stderr)
+		}
+	}
+	var term *terminal
+	if req.pty != nil {
+		if term, err = openTerminal(cmd, req.pty); err != nil {
+			return newCommandError(req, err, nil, 0)
+		}
+	}
+
+	signal := func(sig os.Signal) error {
+		return cmd.Process.Signal(sig)
+	}
+	var group *processGroup
+	if req.processGroup {
+		group = newProcessGroup(cmd, clock)
+		defer group.release()
+		signal = group.signal
+		cmd.Cancel = func() error {
+			return group.stop(req.stopSignal, req.stopGracePeriod)
+		}
+	}
+
+	if req.cgroup != nil {
+		release, cgroupErr := prepareCgroup(cmd, req.cgroup)
+		if cgroupErr != nil {
+			return newCommandError(req, cgroupErr, nil, 0)
+		}
+		// A cgroup left behind is reported even when the command succeeded.
+		defer func() {
+			if releaseErr := release(); releaseErr != nil && err == nil {
+				err = newCommandError(req, releaseErr, nil, 0)
+			} else if releaseErr != nil {
+				err = errors.Join(err, releaseErr)
+			}
+		}()
+	}
+	if s.onStart != nil {
+		prepareSignals(cmd)
+	}
+	req.customize(cmd)
+
+	start := clock.Now()
+	err = cmd.Start()
+	if err == nil {
+		if err = req.started(cmd, group); err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		}
+	}
+	if err == nil {
+		if term != nil {
+			term.started()
+		}
+		if s.onStart != nil {
+			s.onStart(cmd.Process, signal)
+		}
+		err = cmd.Wait()
+		if s.onExit != nil {
+			s.onExit()
+		}
+		if term != nil {
+			term.wait(ctx)
+		}
+	} else if term != nil {
+		term.close()
+	}
+	if err == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, errIdleTimeout) {
+		err = &TimeoutError{Timeout: req.idleTimeout, Idle: true, Err: err}
+	} else if errors.Is(cause, errCommandTimeout) {
+		err = &TimeoutError{Timeout: req.timeout, Err: err}
+	} else if req.ctx.Err() != nil {
+		err = &canceledError{cause: context.Cause(req.ctx), err: err}
+	}
+	return newCommandError(req, err, bytes.Clone(stderrTail.Bytes()), clock.Now().Sub(start))
 }
 
 type execCommand struct {
 	commandRequest
 }
 
+func (e *execCommand) Err() error {
+	return e.err
+}
+
+func (e *execCommand) run(s *streams) error {
+	if e.err != nil {
+		return newCommandError(&e.commandRequest, e.err, nil, 0)
+	}
+	if s.stdin == nil && e.stdin != nil {
+		if !e.stdinClaimed.CompareAndSwap(false, true) {
+			return newCommandError(&e.commandRequest, ErrStdinConsumed, nil, 0)
+		}
+		s.stdin = e.stdin
+	}
+	exec := e.executor
+	if exec == nil {
+		exec = runProcess
+	}
+	for _, mw := range e.middlewares {
+		exec = mw(exec)
+	}
+	var limited []*limitedCapture
+	if e.maxOutputBytes > 0 {
+		limited = s.limitCaptures(e.maxOutputBytes, e.outputLimitPolicy)
+	}
+	if e.teeStdout != nil || e.teeStderr != nil {
+		s.tee(e.teeStdout, e.teeStderr)
+	}
+	// Each execution gets its own copy of the request, so nothing done while
+	// running leaks into the concurrent or later ones.
+	req := e.commandRequest
+	return checkOutputLimits(limited, s, exec(&req, s))
+}
+
+func (e *execCommand) With(opts ...Option) Runnable {
+	derived := &execCommand{commandRequest: e.commandRequest.clone()}
+	for _, opt := range opts {
+		opt(&derived.commandRequest)
+	}
+	return derived
+}
+
 func (e *execCommand) Run() error {
-	return exec.CommandContext(e.ctx, e.cmd, e.args...).Run()
+	return e.run(&streams{})
 }
 
-func (e *execCommand) RunStdout() ([]byte, error) {
-	return exec.CommandContext(e.ctx, e.cmd, e.args...).Output()
+func (e *execCommand) RunStdout(modifiers ...BytesPostModifier) ([]byte, error) {
+	var stdout bytes.Buffer
+	if err := e.run(captureStreams(nil, &stdout, nil)); err != nil {
+		return stdout.Bytes(), err
+	}
+	return applyBytesModifiers(stdout.Bytes(), modifiers, e.modifierPolicy)
 }
 
 func (e *execCommand) RunStdoutStr(modifiers ...RunnablePostModifier) (string, error) {
-	bytes, err := e.RunStdout()
+	output, err := e.runStr(nil, true, false)
 	if err != nil {
 		return "", err
 	}
-	result := string(bytes)
-	for _, modifier := range modifiers {
-		procRes, procErr := modifier.process(result)
+	return applyModifiers(output, modifiers, e.modifierPolicy)
+}
+
+// runStr runs the command capturing stdout, stderr or both into a pooled
+// buffer, which is released once copied into the returned string.
+func (e *execCommand) runStr(stdin io.Reader, stdout bool, stderr bool) (string, error) {
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+	var s *streams
+	switch {
+	case stdout && stderr:
+		s = captureStreams(stdin, buffer, buffer)
+	case stderr:
+		s = captureStreams(stdin, nil, buffer)
+	default:
+		s = captureStreams(stdin, buffer, nil)
+	}
+	err := e.run(s)
+	return buffer.String(), err
+}
+
+func (e *execCommand) RunLines(modifiers ...RunnablePostModifier) ([]string, error) {
+	output, err := e.runStr(nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) == 0 {
+		return []string{}, nil
+	}
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	var errs []error
+	for index, line := range lines {
+		processed, procErr := applyModifiers(line, modifiers, e.modifierPolicy)
 		if procErr != nil {
-			return result, errors.Join(err, procErr)
+			procErr = fmt.Errorf("line %d: %w", index+1, procErr)
+			switch e.modifierPolicy {
+			case ModifierSkipAndContinue:
+				errs = append(errs, procErr)
+			case ModifierReturnPartial:
+				return lines[:index], procErr
+			default:
+				return nil, procErr
+			}
 		}
-		result = procRes
+		lines[index] = processed
+	}
+	return lines, errors.Join(errs...)
+}
+
+// RunNullDelimited splits stdout on NUL bytes, as printed by find -print0 or
+// git ls-files -z, so entries can contain any other character.
+func (e *execCommand) RunNullDelimited() ([]string, error) {
+	output, err := e.runStr(nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) == 0 {
+		return []string{}, nil
 	}
-	return result, nil
+	return strings.Split(strings.TrimSuffix(output, "\x00"), "\x00"), nil
 }
 
-func (e *execCommand) RunCombinedStr() (string, error) {
-	bytes, err := e.RunCombined()
+func (e *execCommand) RunStderr(modifiers ...BytesPostModifier) ([]byte, error) {
+	var stderr bytes.Buffer
+	if err := e.run(captureStreams(nil, nil, &stderr)); err != nil {
+		return stderr.Bytes(), err
+	}
+	return applyBytesModifiers(stderr.Bytes(), modifiers, e.modifierPolicy)
+}
+
+func (e *execCommand) RunStderrStr(modifiers ...RunnablePostModifier) (string, error) {
+	output, err := e.runStr(nil, false, true)
 	if err != nil {
 		return "", err
 	}
-	return string(bytes), nil
+	return applyModifiers(output, modifiers, e.modifierPolicy)
 }
 
-func (e *execCommand) RunCombined() ([]byte, error) {
-	return exec.CommandContext(e.ctx, e.cmd, e.args...).CombinedOutput()
+func (e *execCommand) RunCombinedStr(modifiers ...RunnablePostModifier) (string, error) {
+	output, err := e.runStr(nil, true, true)
+	if err != nil {
+		return "", err
+	}
+	return applyModifiers(output, modifiers, e.modifierPolicy)
 }
 
-func (e *execCommand) RunToWriter(stdout io.Writer, stderr io.Writer) error {
-	cmd := exec.CommandContext(e.ctx, e.cmd, e.args...)
-	if stdout != nil {
-		cmd.Stdout = stdout
+func (e *execCommand) RunCombined(modifiers ...BytesPostModifier) ([]byte, error) {
+	var output bytes.Buffer
+	if err := e.run(captureStreams(nil, &output, &output)); err != nil {
+		return output.Bytes(), err
 	}
-	if stderr != nil {
-		cmd.Stderr = stderr
+	return applyBytesModifiers(output.Bytes(), modifiers, e.modifierPolicy)
+}
+
+func (e *execCommand) RunWithInput(input []byte) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := e.run(captureStreams(bytes.NewReader(input), &stdout, nil))
+	return stdout.Bytes(), err
+}
+
+func (e *execCommand) RunWithInputStr(input string, modifiers ...RunnablePostModifier) (string, error) {
+	output, err := e.runStr(strings.NewReader(input), true, false)
+	if err != nil {
+		return "", err
 	}
-	return cmd.Run()
+	return applyModifiers(output, modifiers, e.modifierPolicy)
+}
+
+func (e *execCommand) RunCombinedWithInput(input []byte) ([]byte, error) {
+	var output bytes.Buffer
+	err := e.run(captureStreams(bytes.NewReader(input), &output, &output))
+	return output.Bytes(), err
+}
+
+func (e *execCommand) RunToWriter(stdout io.Writer, stderr io.Writer) error {
+	return e.run(&streams{stdout: stdout, stderr: stderr})
+}
+
+func (e *execCommand) RunIO(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	return e.run(&streams{stdin: stdin, stdout: stdout, stderr: stderr})
 }
 
 type CommandFactory interface {
 	Command(ctx context.Context, cmd string, args ...string) Runnable
 }
 
-type execCmdFactory struct{}
+type FactoryOption func(*execCmdFactory)
+
+// WithLookPath resolves executables when commands are created, a missing one
+// is reported by the Runnable Err method and returned by every Run method.
+func WithLookPath() FactoryOption {
+	return func(f *execCmdFactory) {
+		f.lookPath = true
+	}
+}
+
+type execCmdFactory struct {
+	lookPath bool
+	defaults []Option
+}
+
+func NewExecCmdFactory(opts ...FactoryOption) CommandFactory {
+	factory := &execCmdFactory{}
+	for _, opt := range opts {
+		opt(factory)
+	}
+	return factory
+}
 
-func NewExecCmdFactory() CommandFactory { return &execCmdFactory{} }
-func (*execCmdFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
-	return &execCommand{commandRequest: commandRequest{ctx, cmd, args}}
+func (f *execCmdFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	req := commandRequest{ctx: ctx, cmd: cmd, args: slices.Clone(args)}
+	for _, opt := range f.defaults {
+		opt(&req)
+	}
+	if f.lookPath && req.err == nil {
+		if _, err := req.lookPath(); err != nil {
+			req.err = fmt.Errorf("%w: %w", ErrExecutableNotFound, err)
+		}
+	}
+	return &execCommand{commandRequest: req}
 }
@@ -0,0 +1,287 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestRunWithInput(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "cat; echo err >&2")
+	output, err := r.RunWithInput([]byte("input\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(output) != "input\n" {
+		t.Errorf("expected stdout to echo the input, got %q", output)
+	}
+
+	combined, err := r.RunCombinedWithInput([]byte("input\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(combined) != "input\nerr\n" {
+		t.Errorf("expected combined output, got %q", combined)
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	t.Setenv("COMMAND_TEST_INHERITED", "inherited")
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `echo "$COMMAND_TEST_INHERITED $A $B"`).
+		With(command.WithEnv(map[string]string{"A": "1"}), command.WithEnv(map[string]string{"B": "2"}))
+	output, err := r.RunStdoutStr(command.NewTrimPostModifier(command.PostModifierTrimRight, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "inherited 1 2" {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestWithEnvInherit(t *testing.T) {
+	t.Setenv("COMMAND_TEST_INHERITED", "inherited")
+	output, err := command.NewExecCmdFactory().Command(context.Background(), "env").
+		With(command.WithEnv(map[string]string{"A": "1"}), command.WithEnvInherit(false)).
+		RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "A=1\n" {
+		t.Errorf("expected only the given environment, got %q", output)
+	}
+}
+
+func TestRunOutputOnFailure(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo partial; echo failed >&2; exit 1")
+	stdout, err := r.RunStdout()
+	if err == nil || string(stdout) != "partial\n" {
+		t.Errorf("RunStdout: expected the partial output and an error, got %q and %v", stdout, err)
+	}
+	combined, err := r.RunCombined()
+	if err == nil || string(combined) != "partial\nfailed\n" {
+		t.Errorf("RunCombined: expected the partial output and an error, got %q and %v", combined, err)
+	}
+	for name, run := range map[string]func() (string, error){
+		"RunStdoutStr":    func() (string, error) { return r.RunStdoutStr() },
+		"RunStderrStr":    func() (string, error) { return r.RunStderrStr() },
+		"RunCombinedStr":  func() (string, error) { return r.RunCombinedStr() },
+		"RunWithInputStr": func() (string, error) { return r.RunWithInputStr("input") },
+	} {
+		if output, err := run(); err == nil || output != "" {
+			t.Errorf("%s: expected an empty output and an error, got %q and %v", name, output, err)
+		}
+	}
+}
+
+func TestWithDir(t *testing.T) {
+	dir := t.TempDir()
+	output, err := command.NewExecCmdFactory().Command(context.Background(), "pwd", "-P").
+		With(command.WithDir(dir)).
+		RunStdoutStr(command.NewTrimPostModifier(command.PostModifierTrimRight, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != expected {
+		t.Errorf("expected the command to run in %s, got %s", expected, output)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sleep", "10").
+		With(command.WithTimeout(50 * time.Millisecond))
+	start := time.Now()
+	err := r.Run()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command was not stopped by the timeout, ran for %s", elapsed)
+	}
+	var timeoutErr *command.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("expected a TimeoutError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the error to be a timeout, got %v", err)
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	factory := command.NewExecCmdFactory()
+	lines, err := factory.Command(context.Background(), "printf", `a\n b \nc\n`).RunLines(command.NewTrimPostModifier(command.PostModifierTrimBoth, " \t\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(lines, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected lines %q", lines)
+	}
+
+	lines, err = factory.Command(context.Background(), "true").RunLines()
+	if err != nil || lines == nil || len(lines) != 0 {
+		t.Errorf("expected no lines for an empty output, got %q and %v", lines, err)
+	}
+}
+
+func TestWithLookPath(t *testing.T) {
+	factory := command.NewExecCmdFactory(command.WithLookPath())
+	if err := factory.Command(context.Background(), "sh", "-c", "true").Err(); err != nil {
+		t.Errorf("unexpected error for an executable in PATH: %v", err)
+	}
+	r := factory.Command(context.Background(), "no-such-command-in-path")
+	if !errors.Is(r.Err(), command.ErrExecutableNotFound) {
+		t.Errorf("expected ErrExecutableNotFound, got %v", r.Err())
+	}
+	// Without the option the error only shows when running.
+	r = command.NewExecCmdFactory().Command(context.Background(), "no-such-command-in-path")
+	if r.Err() != nil {
+		t.Errorf("unexpected error before running: %v", r.Err())
+	}
+	if err := r.Run(); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestWithStdinReader(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "cat").
+		With(command.WithStdinReader(strings.NewReader("streamed")))
+	derived := r.With(command.WithArgs("-"))
+	output, err := derived.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "streamed" {
+		t.Errorf("expected the reader content, got %q", output)
+	}
+	// The reader is shared with the Runnable it was derived from.
+	if err := r.Run(); !errors.Is(err, command.ErrStdinConsumed) {
+		t.Errorf("expected ErrStdinConsumed, got %v", err)
+	}
+	// Run methods taking an input do not use the reader.
+	if output, err := r.RunWithInput([]byte("given")); err != nil || string(output) != "given" {
+		t.Errorf("expected the given input, got %q and %v", output, err)
+	}
+}
+
+func TestRunWithInputStr(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "tr", "a-z", "A-Z")
+	output, err := r.RunWithInputStr("hello\n", command.NewTrimPostModifier(command.PostModifierTrimRight, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "HELLO" {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestRunStderr(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo out; echo ' err ' >&2")
+	stderr, err := r.RunStderr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(stderr) != " err \n" {
+		t.Errorf("expected only stderr, got %q", stderr)
+	}
+	stderrStr, err := r.RunStderrStr(command.NewTrimPostModifier(command.PostModifierTrimBoth, " \t\n"))
+	if err != nil || stderrStr != "err" {
+		t.Errorf("unexpected stderr %q and error %v", stderrStr, err)
+	}
+
+	stderr, err = command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo failed >&2; exit 1").RunStderr()
+	if err == nil || string(stderr) != "failed\n" {
+		t.Errorf("expected the stderr of the failed command, got %q and %v", stderr, err)
+	}
+}
+
+func TestRunIO(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "cat; echo err >&2")
+	var stdout, stderr strings.Builder
+	if err := r.RunIO(strings.NewReader("in\n"), &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "in\n" || stderr.String() != "err\n" {
+		t.Errorf("unexpected output %q and %q", stdout.String(), stderr.String())
+	}
+
+	var combined strings.Builder
+	if err := r.RunIO(strings.NewReader("in\n"), &combined, &combined); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if combined.String() != "in\nerr\n" {
+		t.Errorf("unexpected combined output %q", combined.String())
+	}
+}
+
+func TestWithCmdCustomizer(t *testing.T) {
+	var prepared *exec.Cmd
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `echo "$CUSTOMIZED"`).
+		With(command.WithCmdCustomizer(func(cmd *exec.Cmd) {
+			prepared = cmd
+			cmd.Env = append(os.Environ(), "CUSTOMIZED=yes")
+		}))
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "yes\n" || prepared == nil || prepared.Stdout == nil {
+		t.Errorf("expected the customizer to get the wired command, got output %q", output)
+	}
+}
+
+func TestWithProcAttr(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `echo $$ $(ps -o pgid= -p $$)`).
+		With(command.WithProcAttr(func(attr *syscall.SysProcAttr) { attr.Setpgid = true }))
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		t.Skipf("ps is not available: %v", err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 || fields[0] != fields[1] {
+		t.Errorf("expected the command to lead its own process group, got pid and pgid %q", fields)
+	}
+}
+
+func TestCommandCopiesItsArguments(t *testing.T) {
+	args := []string{"a", "b"}
+	r := command.NewExecCmdFactory().Command(context.Background(), "echo", args...)
+	args[0] = "changed"
+	if out, err := r.RunStdoutStr(); err != nil || out != "a b\n" {
+		t.Errorf("expected the arguments given to Command, got %q and %v", out, err)
+	}
+}
+
+func TestWithLeavesTheOriginalUntouched(t *testing.T) {
+	base := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `echo "$A" "$@"`, "sh").
+		With(command.WithEnv(map[string]string{"A": "base"}))
+	derived := base.With(command.WithArgs("plan"), command.WithEnv(map[string]string{"A": "derived"}))
+	for r, expected := range map[command.Runnable]string{base: "base\n", derived: "derived plan\n"} {
+		if out, err := r.RunStdoutStr(); err != nil || out != expected {
+			t.Errorf("expected %q, got %q and %v", expected, out, err)
+		}
+	}
+}
+
+func TestRunNullDelimited(t *testing.T) {
+	entries, err := command.NewExecCmdFactory().Command(context.Background(), "printf", `a b\0new\nline\0`).RunNullDelimited()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(entries, []string{"a b", "new\nline"}) {
+		t.Errorf("unexpected entries %q", entries)
+	}
+	if entries, err := command.NewExecCmdFactory().Command(context.Background(), "true").RunNullDelimited(); err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries, got %q and %v", entries, err)
+	}
+}
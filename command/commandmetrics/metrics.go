@@ -0,0 +1,144 @@
+// Package commandmetrics exposes Prometheus metrics of the executions of the
+// command package.
+package commandmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ResultSuccess  = "success"
+	ResultExit     = "exit_error"
+	ResultTimeout  = "timeout"
+	ResultCanceled = "canceled"
+	ResultError    = "error"
+)
+
+type Options struct {
+	// Namespace prefixes the metric names.
+	Namespace string
+	// Buckets of the duration histogram, prometheus.DefBuckets when nil.
+	Buckets []float64
+}
+
+// Metrics holds the collectors, labeled by the base name of the command:
+// executions_total by result, duration_seconds, running and output_bytes_total
+// by stream.
+type Metrics struct {
+	executions  *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	running     *prometheus.GaugeVec
+	outputBytes *prometheus.CounterVec
+}
+
+func New(reg prometheus.Registerer, opts Options) (*Metrics, error) {
+	buckets := opts.Buckets
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+	m := &Metrics{
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Subsystem: "command",
+			Name:      "executions_total",
+			Help:      "Number of finished command executions by result.",
+		}, []string{"command", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Subsystem: "command",
+			Name:      "duration_seconds",
+			Help:      "Duration of the command executions.",
+			Buckets:   buckets,
+		}, []string{"command"}),
+		running: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: opts.Namespace,
+			Subsystem: "command",
+			Name:      "running",
+			Help:      "Number of commands running.",
+		}, []string{"command"}),
+		outputBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Subsystem: "command",
+			Name:      "output_bytes_total",
+			Help:      "Bytes written by the commands to their used streams.",
+		}, []string{"command", "stream"}),
+	}
+	for _, collector := range []prometheus.Collector{m.executions, m.duration, m.running, m.outputBytes} {
+		if err := reg.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register command metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Factory returns a factory recording the metrics of every command of inner.
+func (m *Metrics) Factory(inner command.CommandFactory) command.CommandFactory {
+	return command.NewMiddlewareFactory(inner, m.Middleware())
+}
+
+func (m *Metrics) Middleware() command.Middleware {
+	return func(next command.ExecFunc) command.ExecFunc {
+		return func(inv *command.Invocation) error {
+			name := filepath.Base(inv.Cmd)
+			running := m.running.WithLabelValues(name)
+			running.Inc()
+			defer running.Dec()
+			if inv.CombinedOutput() {
+				inv.Stdout = m.counter(inv.Stdout, name, "combined")
+				inv.Stderr = inv.Stdout
+			} else {
+				inv.Stdout = m.counter(inv.Stdout, name, command.StreamStdout.String())
+				inv.Stderr = m.counter(inv.Stderr, name, command.StreamStderr.String())
+			}
+			clock := inv.Clock()
+			start := clock.Now()
+			err := next(inv)
+			m.duration.WithLabelValues(name).Observe(clock.Now().Sub(start).Seconds())
+			m.executions.WithLabelValues(name, Result(err)).Inc()
+			return err
+		}
+	}
+}
+
+func (m *Metrics) counter(w io.Writer, name string, stream string) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return &countingWriter{writer: w, counter: m.outputBytes.WithLabelValues(name, stream)}
+}
+
+// Result classifies an execution error into one of the result labels.
+func Result(err error) string {
+	var timeoutErr *command.TimeoutError
+	var cmdErr *command.CommandError
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case errors.As(err, &timeoutErr):
+		return ResultTimeout
+	case errors.Is(err, context.Canceled):
+		return ResultCanceled
+	case errors.As(err, &cmdErr) && cmdErr.ExitCode() >= 0:
+		return ResultExit
+	default:
+		return ResultError
+	}
+}
+
+type countingWriter struct {
+	writer  io.Writer
+	counter prometheus.Counter
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.counter.Add(float64(n))
+	return n, err
+}
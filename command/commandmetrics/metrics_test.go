@@ -0,0 +1,126 @@
+package commandmetrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandmetrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gather returns the value of every series of reg, keyed by its name and
+// labels, the count of observations for histograms.
+func gather(t *testing.T, reg prometheus.Gatherer) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+			series := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case metric.GetCounter() != nil:
+				values[series] = metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				values[series] = metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				values[series] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func TestMetricsFactory(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	metrics, err := commandmetrics.New(reg, commandmetrics.Options{Namespace: "agent"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		if _, err := io.WriteString(inv.Stdout, "hello"); err != nil {
+			return err
+		}
+		_, _ = io.WriteString(inv.Stderr, "!")
+		if len(inv.Args) > 0 {
+			return &command.ExitStatusError{Code: 1}
+		}
+		return nil
+	})
+	factory := metrics.Factory(inner)
+	ctx := context.Background()
+	if _, err := factory.Command(ctx, "/bin/tool").RunStdout(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := factory.Command(ctx, "tool", "fail").RunStdout(); err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	if _, err := factory.Command(ctx, "tool").RunCombined(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values := gather(t, reg)
+	for series, expected := range map[string]float64{
+		"agent_command_executions_total{command=tool,result=exit_error}": 1,
+		"agent_command_executions_total{command=tool,result=success}":    2,
+		"agent_command_output_bytes_total{command=tool,stream=combined}": 6,
+		"agent_command_output_bytes_total{command=tool,stream=stdout}":   10,
+		"agent_command_running{command=tool}":                            0,
+		"agent_command_duration_seconds{command=tool}":                   3,
+	} {
+		if value, ok := values[series]; !ok || value != expected {
+			t.Errorf("expected %s to be %v, got %v", series, expected, values)
+		}
+	}
+}
+
+func TestMetricsRegistersOnce(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := commandmetrics.New(reg, commandmetrics.Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := commandmetrics.New(reg, commandmetrics.Options{}); err == nil {
+		t.Error("expected registering the metrics twice to fail")
+	}
+}
+
+func TestResult(t *testing.T) {
+	factory := command.NewFuncFactory(func(inv *command.Invocation) error {
+		switch inv.Cmd {
+		case "exit":
+			return &command.ExitStatusError{Code: 3}
+		case "sleep":
+			<-inv.Context.Done()
+			return inv.Context.Err()
+		case "broken":
+			return errors.New("cannot start")
+		}
+		return nil
+	})
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, test := range []struct {
+		runnable command.Runnable
+		expected string
+	}{
+		{factory.Command(context.Background(), "ok"), commandmetrics.ResultSuccess},
+		{factory.Command(context.Background(), "exit"), commandmetrics.ResultExit},
+		{factory.Command(context.Background(), "sleep").With(command.WithTimeout(time.Millisecond)), commandmetrics.ResultTimeout},
+		{factory.Command(canceled, "sleep"), commandmetrics.ResultCanceled},
+		{factory.Command(context.Background(), "broken"), commandmetrics.ResultError},
+	} {
+		if result := commandmetrics.Result(test.runnable.Run()); result != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package commandtest
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// Call is a command received by one of the factories of this package.
+type Call struct {
+	Cmd  string
+	Args []string
+}
+
+func (c Call) CommandLine() string {
+	return command.Join(append([]string{c.Cmd}, c.Args...)...)
+}
+
+func (c Call) is(cmd string, args []string) bool {
+	return c.Cmd == cmd && slices.Equal(c.Args, args)
+}
+
+// callLog keeps the commands run by a factory and provides the assertions on
+// them.
+type callLog struct {
+	callsMu sync.Mutex
+	calls   []Call
+}
+
+func (l *callLog) add(inv *command.Invocation) {
+	l.callsMu.Lock()
+	defer l.callsMu.Unlock()
+	l.calls = append(l.calls, Call{Cmd: inv.Cmd, Args: slices.Clone(inv.Args)})
+}
+
+// Calls returns the commands run so far, in the order they ran.
+func (l *callLog) Calls() []Call {
+	l.callsMu.Lock()
+	defer l.callsMu.Unlock()
+	return slices.Clone(l.calls)
+}
+
+func (l *callLog) count(cmd string, args []string) int {
+	count := 0
+	for _, call := range l.Calls() {
+		if call.is(cmd, args) {
+			count++
+		}
+	}
+	return count
+}
+
+func (l *callLog) describe() string {
+	calls := l.Calls()
+	if len(calls) == 0 {
+		return "no commands ran"
+	}
+	lines := make([]string, 0, len(calls))
+	for _, call := range calls {
+		lines = append(lines, "\t"+call.CommandLine())
+	}
+	return "commands ran:\n" + strings.Join(lines, "\n")
+}
+
+// AssertRan checks that the command ran at least once with exactly these
+// arguments.
+func (l *callLog) AssertRan(t testing.TB, cmd string, args ...string) bool {
+	t.Helper()
+	if l.count(cmd, args) == 0 {
+		t.Errorf("expected %s to run, %s", (Call{cmd, args}).CommandLine(), l.describe())
+		return false
+	}
+	return true
+}
+
+func (l *callLog) AssertNotRan(t testing.TB, cmd string, args ...string) bool {
+	t.Helper()
+	if count := l.count(cmd, args); count > 0 {
+		t.Errorf("expected %s not to run, ran %d times", (Call{cmd, args}).CommandLine(), count)
+		return false
+	}
+	return true
+}
+
+func (l *callLog) AssertRanTimes(t testing.TB, times int, cmd string, args ...string) bool {
+	t.Helper()
+	if count := l.count(cmd, args); count != times {
+		t.Errorf("expected %s to run %d times, ran %d times, %s", (Call{cmd, args}).CommandLine(), times, count, l.describe())
+		return false
+	}
+	return true
+}
+
+// AssertRanInOrder checks that the commands, each given as the command
+// followed by its arguments, ran in this order. Other commands may have run
+// between them.
+func (l *callLog) AssertRanInOrder(t testing.TB, commands ...[]string) bool {
+	t.Helper()
+	next := 0
+	for _, call := range l.Calls() {
+		if next < len(commands) && len(commands[next]) > 0 && call.is(commands[next][0], commands[next][1:]) {
+			next++
+		}
+	}
+	if next < len(commands) {
+		t.Errorf("expected %s to run after the previous commands, %s", command.Join(commands[next]...), l.describe())
+		return false
+	}
+	return true
+}
+
+// AssertNoCalls checks that no command ran.
+func (l *callLog) AssertNoCalls(t testing.TB) bool {
+	t.Helper()
+	if len(l.Calls()) > 0 {
+		t.Errorf("expected no command to run, %s", l.describe())
+		return false
+	}
+	return true
+}
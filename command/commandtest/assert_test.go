@@ -0,0 +1,62 @@
+package commandtest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestAssertions(t *testing.T) {
+	factory := commandtest.NewMockFactory(t)
+	factory.Expect("systemctl", "stop", "nginx")
+	factory.Expect("cp", "a", "b")
+	factory.Expect("systemctl", "start", "nginx")
+	factory.AssertNoCalls(t)
+	for _, cmd := range [][]string{{"systemctl", "stop", "nginx"}, {"cp", "a", "b"}, {"systemctl", "start", "nginx"}} {
+		if err := factory.Command(context.Background(), cmd[0], cmd[1:]...).Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	factory.AssertRan(t, "systemctl", "stop", "nginx")
+	factory.AssertNotRan(t, "systemctl", "restart", "nginx")
+	factory.AssertRanTimes(t, 1, "cp", "a", "b")
+	factory.AssertRanInOrder(t, []string{"systemctl", "stop", "nginx"}, []string{"systemctl", "start", "nginx"})
+	if calls := factory.Calls(); len(calls) != 3 || calls[1].CommandLine() != "cp a b" {
+		t.Errorf("expected the calls in order, got %v", calls)
+	}
+}
+
+func TestAssertionsReportFailures(t *testing.T) {
+	factory := commandtest.NewMockFactory(t)
+	factory.Expect("ls", "-l").AnyTimes()
+	factory.Expect("pwd").AnyTimes()
+	for _, cmd := range [][]string{{"pwd"}, {"ls", "-l"}} {
+		if err := factory.Command(context.Background(), cmd[0], cmd[1:]...).Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	fake := &fakeT{TB: t}
+	checks := []bool{
+		factory.AssertRan(fake, "ls"),
+		factory.AssertNotRan(fake, "pwd"),
+		factory.AssertRanTimes(fake, 2, "ls", "-l"),
+		factory.AssertRanInOrder(fake, []string{"ls", "-l"}, []string{"pwd"}),
+		factory.AssertNoCalls(fake),
+	}
+	for index, ok := range checks {
+		if ok {
+			t.Errorf("expected assertion %d to fail", index)
+		}
+	}
+	if len(fake.errors) != len(checks) {
+		t.Fatalf("expected a failure per assertion, got %q", fake.errors)
+	}
+	if !strings.Contains(fake.errors[0], "commands ran:\n\tpwd\n\tls -l") {
+		t.Errorf("expected the failure to list the commands ran, got %q", fake.errors[0])
+	}
+	if !strings.HasPrefix(fake.errors[3], "expected pwd to run after the previous commands") {
+		t.Errorf("expected the first command out of order to be reported, got %q", fake.errors[3])
+	}
+}
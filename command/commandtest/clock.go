@@ -0,0 +1,137 @@
+package commandtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// FakeClock is a command.Clock whose time only moves with Advance, so
+// timeouts, backoffs and rate limits can be tested without waiting.
+type FakeClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) command.Timer {
+	return c.schedule(d, make(chan time.Time, 1), nil)
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) command.Timer {
+	return c.schedule(d, nil, f)
+}
+
+func (c *FakeClock) schedule(d time.Duration, ch chan time.Time, f func()) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, ch: ch, fn: f}
+	c.add(t, d)
+	return t
+}
+
+func (c *FakeClock) add(t *fakeTimer, d time.Duration) {
+	t.when = c.now.Add(d)
+	c.timers = append(c.timers, t)
+	c.changed.Broadcast()
+}
+
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for index, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:index], c.timers[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the time forward by d, firing the timers due in the order of
+// their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].when.Before(c.timers[j].when) })
+		if len(c.timers) == 0 || c.timers[0].when.After(target) {
+			break
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		if t.ch != nil {
+			select {
+			case t.ch <- c.now:
+			default:
+			}
+			continue
+		}
+		c.mu.Unlock()
+		t.fn()
+		c.mu.Lock()
+	}
+	c.now = target
+	c.changed.Broadcast()
+	c.mu.Unlock()
+}
+
+// Timers returns the number of timers waiting to fire.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitForTimers blocks until at least n timers are waiting, which tells that
+// the code under test reached the point where it waits for the time to move.
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	ch    chan time.Time
+	fn    func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	stopped := t.clock.remove(t)
+	t.clock.changed.Broadcast()
+	return stopped
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.remove(t)
+	t.clock.add(t, d)
+	return active
+}
@@ -0,0 +1,60 @@
+package commandtest_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := commandtest.NewFakeClock(start)
+	var fired []string
+	clock.AfterFunc(3*time.Second, func() { fired = append(fired, "late at "+clock.Now().Sub(start).String()) })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "early at "+clock.Now().Sub(start).String()) })
+	timer := clock.NewTimer(2 * time.Second)
+	stopped := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("expected Stop to report only the first stop of a pending timer")
+	}
+	if clock.Timers() != 3 {
+		t.Errorf("expected 3 pending timers, got %d", clock.Timers())
+	}
+
+	clock.Advance(2 * time.Second)
+	if !slices.Equal(fired, []string{"early at 1s"}) {
+		t.Errorf("expected only the due function to run at its deadline, got %q", fired)
+	}
+	select {
+	case now := <-timer.C():
+		if !now.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("expected the timer to fire at its deadline, got %v", now)
+		}
+	default:
+		t.Error("expected the timer to fire")
+	}
+	if timer.Reset(time.Second) {
+		t.Error("expected Reset of a fired timer to report it inactive")
+	}
+	clock.Advance(time.Second)
+	if !slices.Equal(fired, []string{"early at 1s", "late at 3s"}) {
+		t.Errorf("expected the later function to run, got %q", fired)
+	}
+	if len(timer.C()) != 1 || !clock.Now().Equal(start.Add(3*time.Second)) || clock.Timers() != 0 {
+		t.Errorf("expected the reset timer to fire and no timer left, got %d pending", clock.Timers())
+	}
+}
+
+func TestFakeClockWaitForTimers(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-clock.NewTimer(time.Minute).C()
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute)
+	<-done
+}
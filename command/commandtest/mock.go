@@ -0,0 +1,173 @@
+// Package commandtest provides fake command factories for testing code built on
+// the command package without running any process.
+package commandtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+var ErrUnexpectedCommand = errors.New("unexpected command")
+
+// MockFactory is a command.CommandFactory answering the commands registered
+// with Expect. Commands without a matching expectation fail the test and
+// expectations not met are reported when the test ends.
+type MockFactory struct {
+	callLog
+	t            testing.TB
+	mu           sync.Mutex
+	expectations []*Expectation
+	factory      command.CommandFactory
+	// quiet leaves the reporting of unexpected and missing commands to the
+	// caller, as Scenario does.
+	quiet bool
+}
+
+func NewMockFactory(t testing.TB) *MockFactory {
+	f := newMockFactory(t, false)
+	t.Cleanup(f.verify)
+	return f
+}
+
+func newMockFactory(t testing.TB, quiet bool) *MockFactory {
+	f := &MockFactory{t: t, quiet: quiet}
+	f.factory = command.NewFuncFactory(f.invoke)
+	return f
+}
+
+func (f *MockFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	return f.factory.Command(ctx, cmd, args...)
+}
+
+// Expect registers a command that is expected once, with exactly these
+// arguments, unless changed with Times or AnyTimes. Expectations are matched
+// in the order they were registered.
+func (f *MockFactory) Expect(cmd string, args ...string) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := &Expectation{cmd: cmd, args: args, times: 1}
+	f.expectations = append(f.expectations, e)
+	return e
+}
+
+func (f *MockFactory) invoke(inv *command.Invocation) error {
+	f.add(inv)
+	e := f.match(inv)
+	if e == nil {
+		if !f.quiet {
+			f.t.Errorf("commandtest: unexpected command %s", inv.CommandLine())
+		}
+		return fmt.Errorf("%w: %s", ErrUnexpectedCommand, inv.CommandLine())
+	}
+	return e.respond(inv)
+}
+
+func (f *MockFactory) match(inv *command.Invocation) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.expectations {
+		if e.matches(inv) && (e.times < 0 || e.calls < e.times) {
+			e.calls++
+			return e
+		}
+	}
+	return nil
+}
+
+func (f *MockFactory) verify() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.expectations {
+		if e.times >= 0 && e.calls < e.times {
+			f.t.Errorf("commandtest: expected %s %d times, ran %d", e.commandLine(), e.times, e.calls)
+		}
+	}
+}
+
+type Expectation struct {
+	cmd    string
+	args   []string
+	stdout string
+	stderr string
+	code   int
+	err    error
+	run    func(inv *command.Invocation) error
+	times  int
+	calls  int
+}
+
+func (e *Expectation) ReturnsStdout(stdout string) *Expectation {
+	e.stdout = stdout
+	return e
+}
+
+func (e *Expectation) ReturnsStderr(stderr string) *Expectation {
+	e.stderr = stderr
+	return e
+}
+
+// ExitCode makes the command fail with code, zero meaning success.
+func (e *Expectation) ExitCode(code int) *Expectation {
+	e.code = code
+	return e
+}
+
+// ReturnsError makes the command fail with err, as when it cannot be started.
+func (e *Expectation) ReturnsError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Do calls fn after the output is written, its error being returned by the
+// command. It can read the stdin of the command or check its environment.
+func (e *Expectation) Do(fn func(inv *command.Invocation) error) *Expectation {
+	e.run = fn
+	return e
+}
+
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes allows the command to run any number of times, none included.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = -1
+	return e
+}
+
+func (e *Expectation) matches(inv *command.Invocation) bool {
+	return inv.Cmd == e.cmd && slices.Equal(inv.Args, e.args)
+}
+
+func (e *Expectation) commandLine() string {
+	return command.Join(append([]string{e.cmd}, e.args...)...)
+}
+
+func (e *Expectation) respond(inv *command.Invocation) error {
+	if _, err := io.WriteString(inv.Stdout, e.stdout); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(inv.Stderr, e.stderr); err != nil {
+		return err
+	}
+	if e.run != nil {
+		if err := e.run(inv); err != nil {
+			return err
+		}
+	}
+	if e.err != nil {
+		return e.err
+	}
+	if e.code != 0 {
+		return &command.ExitStatusError{Code: e.code}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package commandtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+// fakeT records the failures reported through it and keeps the cleanups for
+// the test to run them.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+func (t *fakeT) cleanup() {
+	for index := len(t.cleanups) - 1; index >= 0; index-- {
+		t.cleanups[index]()
+	}
+}
+
+func TestMockFactory(t *testing.T) {
+	factory := commandtest.NewMockFactory(t)
+	factory.Expect("git", "status").ReturnsStdout("clean")
+	factory.Expect("git", "push").ReturnsStderr("rejected").ExitCode(1)
+	out, err := factory.Command(context.Background(), "git", "status").RunStdoutStr()
+	if err != nil || out != "clean" {
+		t.Errorf("expected the stdout of the expectation, got %q and %v", out, err)
+	}
+	_, err = factory.Command(context.Background(), "git", "push").RunStdoutStr()
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 1 {
+		t.Errorf("expected exit code 1, got %v", err)
+	}
+}
+
+func TestMockFactoryDo(t *testing.T) {
+	factory := commandtest.NewMockFactory(t)
+	failure := errors.New("boom")
+	var dir string
+	factory.Expect("make").Do(func(inv *command.Invocation) error {
+		dir = inv.Dir
+		return failure
+	})
+	err := factory.Command(context.Background(), "make").With(command.WithDir("/src")).Run()
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of Do, got %v", err)
+	}
+	if dir != "/src" {
+		t.Errorf("expected Do to see the invocation, got dir %q", dir)
+	}
+}
+
+func TestMockFactoryTimes(t *testing.T) {
+	factory := commandtest.NewMockFactory(t)
+	factory.Expect("true").Times(2)
+	factory.Expect("date").AnyTimes()
+	for range 2 {
+		if err := factory.Command(context.Background(), "true").Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	factory.AssertRanTimes(t, 2, "true")
+	factory.AssertNotRan(t, "date")
+}
+
+func TestMockFactoryReportsUnexpectedCommands(t *testing.T) {
+	fake := &fakeT{TB: t}
+	factory := commandtest.NewMockFactory(fake)
+	factory.Expect("ls")
+	factory.Expect("rm", "-rf", "/tmp/x")
+	err := factory.Command(context.Background(), "ls", "-l").Run()
+	if !errors.Is(err, commandtest.ErrUnexpectedCommand) {
+		t.Errorf("expected ErrUnexpectedCommand, got %v", err)
+	}
+	if err := factory.Command(context.Background(), "ls").Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := factory.Command(context.Background(), "ls").Run(); !errors.Is(err, commandtest.ErrUnexpectedCommand) {
+		t.Errorf("expected the second run of a single expectation to fail, got %v", err)
+	}
+	fake.cleanup()
+	if len(fake.errors) != 3 {
+		t.Fatalf("expected two unexpected commands and an unmet expectation, got %q", fake.errors)
+	}
+	if fake.errors[2] != "commandtest: expected rm -rf /tmp/x 1 times, ran 0" {
+		t.Errorf("expected the unmet expectation to be reported, got %q", fake.errors[2])
+	}
+}
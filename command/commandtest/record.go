@@ -0,0 +1,116 @@
+package commandtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pablintino/commons-go/command"
+	"gopkg.in/yaml.v3"
+)
+
+// RecordingFactory runs the commands with an inner factory and writes each
+// execution as a YAML fixture in a directory, named after its order, so the
+// directory can later be given to NewReplayFactory. Secrets marked with
+// command.WithSecretArgs or command.WithSecretEnv are redacted from the
+// fixtures, whose redacted arguments no longer match the commands: such
+// fixtures need an ArgsPattern, written by hand, to be replayed.
+type RecordingFactory struct {
+	callLog
+	inner command.CommandFactory
+	dir   string
+
+	mu       sync.Mutex
+	count    int
+	recorded []string
+}
+
+// NewRecordingFactory records to dir, which is created if needed.
+func NewRecordingFactory(inner command.CommandFactory, dir string) (*RecordingFactory, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+	return &RecordingFactory{inner: inner, dir: dir}, nil
+}
+
+func (f *RecordingFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(command.WithMiddleware(f.recordExecution))
+}
+
+// Recorded returns the fixture files written so far.
+func (f *RecordingFactory) Recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.recorded...)
+}
+
+func (f *RecordingFactory) recordExecution(next command.ExecFunc) command.ExecFunc {
+	return func(inv *command.Invocation) error {
+		f.add(inv)
+		var stdout, stderr bytes.Buffer
+		if inv.CombinedOutput() {
+			inv.Stdout = io.MultiWriter(inv.Stdout, &stdout)
+			inv.Stderr = inv.Stdout
+		} else {
+			inv.Stdout = teeBuffer(inv.Stdout, &stdout)
+			inv.Stderr = teeBuffer(inv.Stderr, &stderr)
+		}
+		err := next(inv)
+
+		fixture := &Fixture{
+			Command: inv.Cmd,
+			Args:    inv.RedactedArgs(),
+			Stdout:  inv.Redact(stdout.String()),
+			Stderr:  inv.Redact(stderr.String()),
+		}
+		var cmdErr *command.CommandError
+		if err != nil && !errors.As(err, &cmdErr) {
+			return err
+		}
+		if cmdErr != nil {
+			fixture.ExitCode = cmdErr.ExitCode()
+		}
+		if recordErr := f.record(fixture); recordErr != nil {
+			return errors.Join(err, recordErr)
+		}
+		return err
+	}
+}
+
+func teeBuffer(w io.Writer, buffer *bytes.Buffer) io.Writer {
+	if w == nil {
+		return buffer
+	}
+	return io.MultiWriter(w, buffer)
+}
+
+func (f *RecordingFactory) record(fixture *Fixture) error {
+	content, err := yaml.Marshal(fixture)
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count++
+	path := filepath.Join(f.dir, fmt.Sprintf("%04d-%s.yaml", f.count, fixtureName(fixture.Command)))
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	f.recorded = append(f.recorded, path)
+	return nil
+}
+
+func fixtureName(cmd string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, filepath.Base(cmd))
+}
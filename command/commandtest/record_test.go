@@ -0,0 +1,84 @@
+package commandtest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestRecordingFactoryFixturesReplay(t *testing.T) {
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		if _, err := io.WriteString(inv.Stdout, "out "+inv.Args[0]); err != nil {
+			return err
+		}
+		if inv.Args[0] == "fail" {
+			_, _ = io.WriteString(inv.Stderr, "failed")
+			return &command.ExitStatusError{Code: 3}
+		}
+		return nil
+	})
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	recorder, err := commandtest.NewRecordingFactory(inner, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if out, err := recorder.Command(ctx, "/usr/bin/tool", "ok").RunStdoutStr(); err != nil || out != "out ok" {
+		t.Fatalf("expected the inner output, got %q and %v", out, err)
+	}
+	if _, err := recorder.Command(ctx, "/usr/bin/tool", "fail").RunStdoutStr(); err == nil {
+		t.Fatal("expected the inner failure")
+	}
+	recorded := recorder.Recorded()
+	if len(recorded) != 2 || filepath.Base(recorded[0]) != "0001-tool.yaml" {
+		t.Fatalf("expected a fixture per execution, got %q", recorded)
+	}
+	recorder.AssertRanInOrder(t, []string{"/usr/bin/tool", "ok"}, []string{"/usr/bin/tool", "fail"})
+
+	replay, err := commandtest.NewReplayFactory(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out, err := replay.Command(ctx, "/usr/bin/tool", "ok").RunStdoutStr(); err != nil || out != "out ok" {
+		t.Errorf("expected the recorded output, got %q and %v", out, err)
+	}
+	_, err = replay.Command(ctx, "/usr/bin/tool", "fail").RunStdoutStr()
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 3 {
+		t.Errorf("expected the recorded exit code, got %v", err)
+	}
+	if !errors.As(err, &cmdErr) || string(cmdErr.Stderr()) != "failed" {
+		t.Errorf("expected the recorded stderr, got %v", err)
+	}
+}
+
+func TestRecordingFactoryRedactsSecrets(t *testing.T) {
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		_, _ = io.WriteString(inv.Stderr, "bad password "+inv.Args[1])
+		_, err := io.WriteString(inv.Stdout, "logged in with "+inv.Args[1])
+		return err
+	})
+	dir := t.TempDir()
+	recorder, err := commandtest.NewRecordingFactory(inner, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = recorder.Command(context.Background(), "login", "-p", "s3cret").With(command.WithSecretArgs(1)).Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(recorder.Recorded()[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(content), "s3cret") || strings.Count(string(content), "***") != 3 {
+		t.Errorf("expected the secret to be redacted, got %s", content)
+	}
+}
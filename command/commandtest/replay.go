@@ -0,0 +1,185 @@
+package commandtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pablintino/commons-go/command"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrNoFixture = errors.New("no fixture for command")
+
+// Fixture is a canned execution. Files hold a single fixture or a list of
+// them, in JSON or YAML.
+type Fixture struct {
+	Command string   `json:"command" yaml:"command"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty"`
+	// ArgsPattern, when set, is a regular expression matched against the
+	// whole argument list, quoted as a shell would, instead of Args.
+	ArgsPattern string `json:"argsPattern,omitempty" yaml:"argsPattern,omitempty"`
+	Stdout      string `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+	Stderr      string `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+	ExitCode    int    `json:"exitCode,omitempty" yaml:"exitCode,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+func (f *Fixture) matches(inv *command.Invocation) bool {
+	if inv.Cmd != f.Command {
+		return false
+	}
+	if f.pattern != nil {
+		return f.pattern.MatchString(command.Join(inv.Args...))
+	}
+	return slices.Equal(inv.Args, f.Args)
+}
+
+func (f *Fixture) respond(inv *command.Invocation) error {
+	if _, err := io.WriteString(inv.Stdout, f.Stdout); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(inv.Stderr, f.Stderr); err != nil {
+		return err
+	}
+	if f.ExitCode != 0 {
+		return &command.ExitStatusError{Code: f.ExitCode}
+	}
+	return nil
+}
+
+// ReplayFactory answers commands with the first fixture matching them, in the
+// order they were loaded. Commands without a fixture fail with ErrNoFixture.
+type ReplayFactory struct {
+	callLog
+	fixtures []*Fixture
+	factory  command.CommandFactory
+}
+
+// NewReplayFactory loads the fixtures of the given files, directories being
+// read for their .json, .yaml and .yml files in name order.
+func NewReplayFactory(paths ...string) (*ReplayFactory, error) {
+	f := &ReplayFactory{}
+	for _, path := range paths {
+		files, err := fixtureFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			fixtures, err := LoadFixtures(file)
+			if err != nil {
+				return nil, err
+			}
+			f.fixtures = append(f.fixtures, fixtures...)
+		}
+	}
+	f.factory = command.NewFuncFactory(f.invoke)
+	return f, nil
+}
+
+func (f *ReplayFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	return f.factory.Command(ctx, cmd, args...)
+}
+
+func (f *ReplayFactory) invoke(inv *command.Invocation) error {
+	f.add(inv)
+	for _, fixture := range f.fixtures {
+		if fixture.matches(inv) {
+			return fixture.respond(inv)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNoFixture, inv.CommandLine())
+}
+
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".json", ".yaml", ".yml":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// LoadFixtures reads the fixtures of a file, decoded as JSON when it has a
+// .json extension and as YAML otherwise.
+func LoadFixtures(path string) ([]*Fixture, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures []*Fixture
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = decodeJSONFixtures(content, &fixtures)
+	} else {
+		err = decodeYAMLFixtures(content, &fixtures)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fixtures of %s: %w", path, err)
+	}
+	for index, fixture := range fixtures {
+		if fixture.Command == "" {
+			return nil, fmt.Errorf("fixture %d of %s has no command", index, path)
+		}
+		if fixture.ArgsPattern != "" {
+			if fixture.pattern, err = regexp.Compile("^(?:" + fixture.ArgsPattern + ")$"); err != nil {
+				return nil, fmt.Errorf("invalid args pattern in fixture %d of %s: %w", index, path, err)
+			}
+		}
+	}
+	return fixtures, nil
+}
+
+func decodeJSONFixtures(content []byte, fixtures *[]*Fixture) error {
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, fixtures)
+	}
+	fixture := &Fixture{}
+	if err := json.Unmarshal(content, fixture); err != nil {
+		return err
+	}
+	*fixtures = []*Fixture{fixture}
+	return nil
+}
+
+func decodeYAMLFixtures(content []byte, fixtures *[]*Fixture) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return err
+	}
+	if len(node.Content) == 0 {
+		return nil
+	}
+	if node.Content[0].Kind == yaml.SequenceNode {
+		return node.Decode(fixtures)
+	}
+	fixture := &Fixture{}
+	if err := node.Decode(fixture); err != nil {
+		return err
+	}
+	*fixtures = []*Fixture{fixture}
+	return nil
+}
@@ -0,0 +1,71 @@
+package commandtest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func writeFixture(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestReplayFactory(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "a.yaml", `
+- command: git
+  args: [status, --short]
+  stdout: " M go.mod\n"
+- command: git
+  argsPattern: "log .*"
+  stdout: "abc123\n"
+`)
+	writeFixture(t, dir, "b.json", `{"command": "git", "args": ["push"], "stderr": "rejected", "exitCode": 1}`)
+	writeFixture(t, dir, "notes.txt", "ignored")
+	factory, err := commandtest.NewReplayFactory(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if out, err := factory.Command(ctx, "git", "status", "--short").RunStdoutStr(); err != nil || out != " M go.mod\n" {
+		t.Errorf("expected the exact fixture, got %q and %v", out, err)
+	}
+	if out, err := factory.Command(ctx, "git", "log", "-n", "1").RunStdoutStr(); err != nil || out != "abc123\n" {
+		t.Errorf("expected the pattern fixture, got %q and %v", out, err)
+	}
+	_, err = factory.Command(ctx, "git", "push").RunStdoutStr()
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 1 {
+		t.Errorf("expected exit code 1, got %v", err)
+	}
+	if err := factory.Command(ctx, "git", "log").Run(); !errors.Is(err, commandtest.ErrNoFixture) {
+		t.Errorf("expected the pattern to match the whole arguments, got %v", err)
+	}
+	factory.AssertRanTimes(t, 1, "git", "push")
+}
+
+func TestLoadFixturesErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no-command.yaml": "stdout: out",
+		"pattern.json":    `[{"command": "ls", "argsPattern": "("}]`,
+		"invalid.json":    `{"command": `,
+	} {
+		if _, err := commandtest.LoadFixtures(writeFixture(t, dir, name, content)); err == nil {
+			t.Errorf("expected %s to fail", name)
+		}
+	}
+	if _, err := commandtest.NewReplayFactory(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected a missing path to fail")
+	}
+}
@@ -0,0 +1,128 @@
+package commandtest
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// Scenario is a table driven test of code built on a command.CommandFactory.
+// Run gives When a mock answering the commands registered by Given, then
+// checks that these commands, and only them, ran as many times as expected,
+// reporting a diff of the expected and actual executions otherwise.
+type Scenario struct {
+	// Name names the subtest, the scenario runs in the calling test when empty.
+	Name  string
+	Given func(mock *MockFactory)
+	When  func(factory command.CommandFactory) error
+	// Then checks the outcome of When, which is expected to succeed when
+	// Then is nil.
+	Then func(t testing.TB, err error)
+	// AnyOrder accepts the commands in any order, instead of the order Given
+	// registered them.
+	AnyOrder bool
+}
+
+func (s Scenario) Run(t *testing.T) {
+	t.Helper()
+	if s.Name == "" {
+		s.run(t)
+		return
+	}
+	t.Run(s.Name, s.run)
+}
+
+// RunScenarios runs each scenario as a subtest of t.
+func RunScenarios(t *testing.T, scenarios ...Scenario) {
+	t.Helper()
+	for _, s := range scenarios {
+		s.Run(t)
+	}
+}
+
+func (s Scenario) run(t *testing.T) {
+	t.Helper()
+	mock := newMockFactory(t, true)
+	if s.Given != nil {
+		s.Given(mock)
+	}
+	var err error
+	if s.When != nil {
+		err = s.When(mock)
+	}
+
+	expected, actual := mock.executions()
+	if s.AnyOrder {
+		slices.Sort(expected)
+		slices.Sort(actual)
+	}
+	if !slices.Equal(expected, actual) {
+		t.Errorf("executions differ (-expected +actual):\n%s", diffLines(expected, actual))
+	}
+	if s.Then != nil {
+		s.Then(t, err)
+	} else if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// executions returns the command lines expected by the expectations, in
+// the order they were registered, and the ones that ran. Commands allowed
+// any number of times are left out of both.
+func (f *MockFactory) executions() (expected []string, actual []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var optional []*Expectation
+	for _, e := range f.expectations {
+		if e.times < 0 {
+			optional = append(optional, e)
+			continue
+		}
+		for range e.times {
+			expected = append(expected, e.commandLine())
+		}
+	}
+	for _, call := range f.Calls() {
+		if !slices.ContainsFunc(optional, func(e *Expectation) bool { return call.is(e.cmd, e.args) }) {
+			actual = append(actual, call.CommandLine())
+		}
+	}
+	return expected, actual
+}
+
+// diffLines renders the longest common subsequence of both lists unchanged
+// and the rest as removed from expected or added by actual.
+func diffLines(expected, actual []string) string {
+	common := make([][]int, len(expected)+1)
+	for i := range common {
+		common[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			b.WriteString("  " + expected[i] + "\n")
+			i++
+			j++
+		case j == len(actual) || (i < len(expected) && common[i+1][j] >= common[i][j+1]):
+			b.WriteString("- " + expected[i] + "\n")
+			i++
+		default:
+			b.WriteString("+ " + actual[j] + "\n")
+			j++
+		}
+	}
+	return b.String()
+}
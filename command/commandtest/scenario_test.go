@@ -0,0 +1,92 @@
+package commandtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// restartService is code under test stopping and starting a service, checking
+// its status in between.
+func restartService(factory command.CommandFactory) error {
+	ctx := context.Background()
+	if err := factory.Command(ctx, "systemctl", "stop", "nginx").Run(); err != nil {
+		return err
+	}
+	for range 2 {
+		if err := factory.Command(ctx, "systemctl", "is-active", "nginx").Run(); err != nil {
+			break
+		}
+	}
+	return factory.Command(ctx, "systemctl", "start", "nginx").Run()
+}
+
+func TestScenario(t *testing.T) {
+	failure := errors.New("unit not found")
+	RunScenarios(t,
+		Scenario{
+			Name: "restart",
+			Given: func(mock *MockFactory) {
+				mock.Expect("systemctl", "stop", "nginx")
+				mock.Expect("systemctl", "is-active", "nginx").AnyTimes()
+				mock.Expect("systemctl", "start", "nginx")
+			},
+			When: restartService,
+		},
+		Scenario{
+			Name: "any order",
+			Given: func(mock *MockFactory) {
+				mock.Expect("systemctl", "start", "nginx")
+				mock.Expect("systemctl", "stop", "nginx")
+				mock.Expect("systemctl", "is-active", "nginx").Times(2)
+			},
+			When:     restartService,
+			AnyOrder: true,
+		},
+		Scenario{
+			Name: "failure",
+			Given: func(mock *MockFactory) {
+				mock.Expect("systemctl", "stop", "nginx").ReturnsError(failure)
+			},
+			When: restartService,
+			Then: func(t testing.TB, err error) {
+				if !errors.Is(err, failure) {
+					t.Errorf("expected the stop failure, got %v", err)
+				}
+			},
+		},
+	)
+}
+
+func TestScenarioExecutions(t *testing.T) {
+	mock := newMockFactory(t, true)
+	mock.Expect("a").Times(2)
+	mock.Expect("b", "x")
+	mock.Expect("c").AnyTimes()
+	for _, cmd := range []string{"a", "c", "d", "c"} {
+		_ = mock.Command(context.Background(), cmd).Run()
+	}
+	expected, actual := mock.executions()
+	if diff := diffLines(expected, actual); diff != "  a\n- a\n- b x\n+ d\n" {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, test := range []struct {
+		expected []string
+		actual   []string
+		diff     string
+	}{
+		{nil, nil, ""},
+		{[]string{"a", "b"}, []string{"a", "b"}, "  a\n  b\n"},
+		{[]string{"a", "b", "c"}, []string{"a", "c", "d"}, "  a\n- b\n  c\n+ d\n"},
+		{[]string{"a"}, []string{"b"}, "- a\n+ b\n"},
+	} {
+		if diff := diffLines(test.expected, test.actual); diff != test.diff {
+			t.Errorf("diffLines(%q, %q): expected %q, got %q", test.expected, test.actual, test.diff, diff)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package commandtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// ArgsMatcher decides if a stub rule applies to the arguments of a command.
+type ArgsMatcher func(args []string) bool
+
+func ArgsExact(args ...string) ArgsMatcher {
+	return func(actual []string) bool {
+		return slices.Equal(actual, args)
+	}
+}
+
+func ArgsPrefix(prefix ...string) ArgsMatcher {
+	return func(actual []string) bool {
+		return len(actual) >= len(prefix) && slices.Equal(actual[:len(prefix)], prefix)
+	}
+}
+
+// ArgsRegexp matches pattern against the arguments quoted as a shell would
+// and joined by spaces.
+func ArgsRegexp(pattern *regexp.Regexp) ArgsMatcher {
+	return func(actual []string) bool {
+		return pattern.MatchString(command.Join(actual...))
+	}
+}
+
+// StubFactory is a command.CommandFactory answering commands with the first
+// rule matching them, in the order rules were added. Commands matching no
+// rule fail with ErrUnexpectedCommand.
+type StubFactory struct {
+	callLog
+	mu      sync.Mutex
+	rules   []*StubRule
+	factory command.CommandFactory
+}
+
+func Stub() *StubFactory {
+	s := &StubFactory{}
+	s.factory = command.NewFuncFactory(s.invoke)
+	return s
+}
+
+func (s *StubFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	return s.factory.Command(ctx, cmd, args...)
+}
+
+// When adds a rule for cmd, any command when empty, whose arguments satisfy
+// matcher, nil matching any arguments.
+func (s *StubFactory) When(cmd string, matcher ArgsMatcher) *StubRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rule := &StubRule{StubFactory: s, cmd: cmd, matcher: matcher, responses: []*stubResponse{{}}}
+	s.rules = append(s.rules, rule)
+	return rule
+}
+
+func (s *StubFactory) WhenArgs(args ...string) *StubRule {
+	return s.When("", ArgsExact(args...))
+}
+
+func (s *StubFactory) WhenArgsPrefix(prefix ...string) *StubRule {
+	return s.When("", ArgsPrefix(prefix...))
+}
+
+func (s *StubFactory) WhenArgsMatch(pattern *regexp.Regexp) *StubRule {
+	return s.When("", ArgsRegexp(pattern))
+}
+
+func (s *StubFactory) invoke(inv *command.Invocation) error {
+	s.add(inv)
+	response := s.respond(inv)
+	if response == nil {
+		return fmt.Errorf("%w: %s", ErrUnexpectedCommand, inv.CommandLine())
+	}
+	if _, err := io.WriteString(inv.Stdout, response.stdout); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(inv.Stderr, response.stderr); err != nil {
+		return err
+	}
+	if response.err != nil {
+		return response.err
+	}
+	if response.code != 0 {
+		return &command.ExitStatusError{Code: response.code}
+	}
+	return nil
+}
+
+func (s *StubFactory) respond(inv *command.Invocation) *stubResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rule := range s.rules {
+		if rule.cmd != "" && rule.cmd != inv.Cmd || rule.matcher != nil && !rule.matcher(inv.Args) {
+			continue
+		}
+		response := rule.responses[min(rule.calls, len(rule.responses)-1)]
+		rule.calls++
+		return response
+	}
+	return nil
+}
+
+// StubRule sets the response of the commands matching it. It embeds its
+// factory so rules can be chained and the factory used right away.
+type StubRule struct {
+	*StubFactory
+	cmd       string
+	matcher   ArgsMatcher
+	responses []*stubResponse
+	calls     int
+}
+
+type stubResponse struct {
+	stdout string
+	stderr string
+	code   int
+	err    error
+}
+
+func (r *StubRule) current() *stubResponse {
+	return r.responses[len(r.responses)-1]
+}
+
+func (r *StubRule) Stdout(stdout string) *StubRule {
+	r.current().stdout = stdout
+	return r
+}
+
+func (r *StubRule) Stderr(stderr string) *StubRule {
+	r.current().stderr = stderr
+	return r
+}
+
+func (r *StubRule) ExitCode(code int) *StubRule {
+	r.current().code = code
+	return r
+}
+
+func (r *StubRule) Err(err error) *StubRule {
+	r.current().err = err
+	return r
+}
+
+// Then starts the response given to the next matching call, the last
+// response being repeated once all were given.
+func (r *StubRule) Then() *StubRule {
+	r.responses = append(r.responses, &stubResponse{})
+	return r
+}
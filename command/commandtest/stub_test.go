@@ -0,0 +1,62 @@
+package commandtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestStubMatchers(t *testing.T) {
+	failure := errors.New("not found")
+	stub := commandtest.Stub().
+		WhenArgs("status").Stdout("clean").
+		WhenArgsPrefix("log", "-n").Stdout("log").
+		WhenArgsMatch(regexp.MustCompile(`^show [0-9a-f]+$`)).Err(failure).
+		When("kubectl", func(args []string) bool { return slices.Contains(args, "--dry-run") }).Stdout("dry")
+	ctx := context.Background()
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"git", "status"}, "clean"},
+		{[]string{"git", "log", "-n", "3"}, "log"},
+		{[]string{"kubectl", "apply", "--dry-run"}, "dry"},
+	} {
+		if out, err := stub.Command(ctx, test.args[0], test.args[1:]...).RunStdoutStr(); err != nil || out != test.expected {
+			t.Errorf("%v: expected %q, got %q and %v", test.args, test.expected, out, err)
+		}
+	}
+	if err := stub.Command(ctx, "git", "show", "abc123").Run(); !errors.Is(err, failure) {
+		t.Errorf("expected the error of the rule, got %v", err)
+	}
+	for _, args := range [][]string{{"git", "status", "-s"}, {"git", "log"}, {"kubectl", "apply"}} {
+		if err := stub.Command(ctx, args[0], args[1:]...).Run(); !errors.Is(err, commandtest.ErrUnexpectedCommand) {
+			t.Errorf("%v: expected ErrUnexpectedCommand, got %v", args, err)
+		}
+	}
+}
+
+func TestStubSuccessiveResponses(t *testing.T) {
+	stub := commandtest.Stub().
+		When("curl", nil).Stderr("refused").ExitCode(7).
+		Then().Stdout("ok")
+	var results []string
+	for range 3 {
+		out, err := stub.Command(context.Background(), "curl", "http://localhost").RunStdoutStr()
+		code := 0
+		var cmdErr *command.CommandError
+		if errors.As(err, &cmdErr) {
+			code = cmdErr.ExitCode()
+		}
+		results = append(results, fmt.Sprintf("%s:%d", out, code))
+	}
+	if !slices.Equal(results, []string{":7", "ok:0", "ok:0"}) {
+		t.Errorf("expected the responses in order and the last one repeated, got %q", results)
+	}
+}
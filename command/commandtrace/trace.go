@@ -0,0 +1,88 @@
+// Package commandtrace creates OpenTelemetry spans for the executions of the
+// command package.
+package commandtrace
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/pablintino/commons-go/command"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/pablintino/commons-go/command/commandtrace"
+
+type Options struct {
+	// TracerProvider defaults to the global one.
+	TracerProvider trace.TracerProvider
+	// SpanName defaults to "exec" followed by the base name of the command.
+	SpanName func(inv *command.Invocation) string
+	// InjectEnv sets TRACEPARENT and TRACESTATE on the commands so tools
+	// supporting them continue the trace.
+	InjectEnv bool
+}
+
+// NewFactory traces every command of inner, see Middleware.
+func NewFactory(inner command.CommandFactory, opts Options) command.CommandFactory {
+	return command.NewMiddlewareFactory(inner, Middleware(opts))
+}
+
+// Middleware starts a span for each execution, child of the span of the
+// command context, which is given to the rest of the execution. Arguments
+// are recorded with their secrets redacted.
+func Middleware(opts Options) command.Middleware {
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	tracer := provider.Tracer(instrumentationName)
+	spanName := opts.SpanName
+	if spanName == nil {
+		spanName = func(inv *command.Invocation) string {
+			return "exec " + filepath.Base(inv.Cmd)
+		}
+	}
+	return func(next command.ExecFunc) command.ExecFunc {
+		return func(inv *command.Invocation) error {
+			ctx, span := tracer.Start(inv.Context, spanName(inv),
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(
+					attribute.String("process.executable.name", filepath.Base(inv.Cmd)),
+					attribute.String("process.executable.path", inv.Cmd),
+					attribute.StringSlice("process.command_args", append([]string{inv.Cmd}, inv.RedactedArgs()...)),
+				))
+			defer span.End()
+			if inv.Dir != "" {
+				span.SetAttributes(attribute.String("process.working_directory", inv.Dir))
+			}
+			inv.Context = ctx
+			if opts.InjectEnv {
+				carrier := propagation.MapCarrier{}
+				propagation.TraceContext{}.Inject(ctx, carrier)
+				for key, value := range carrier {
+					inv.Env[strings.ToUpper(key)] = value
+				}
+			}
+
+			clock := inv.Clock()
+			start := clock.Now()
+			err := next(inv)
+			span.SetAttributes(attribute.Int64("command.duration_ms", clock.Now().Sub(start).Milliseconds()))
+			var cmdErr *command.CommandError
+			if errors.As(err, &cmdErr) && cmdErr.ExitCode() >= 0 {
+				span.SetAttributes(attribute.Int("process.exit.code", cmdErr.ExitCode()))
+			} else if err == nil {
+				span.SetAttributes(attribute.Int("process.exit.code", 0))
+			}
+			if err != nil {
+				span.SetStatus(codes.Error, inv.Redact(err.Error()))
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package commandtrace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+	"github.com/pablintino/commons-go/command/commandtrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingProvider hands out spans keeping what is set on them.
+type recordingProvider struct {
+	noop.TracerProvider
+	spans []*recordingSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingProvider
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+	span := &recordingSpan{
+		name:       name,
+		parent:     trace.SpanContextFromContext(ctx),
+		attributes: map[attribute.Key]attribute.Value{},
+		context: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{byte(len(t.provider.spans) + 1)},
+			TraceFlags: trace.FlagsSampled,
+		}),
+	}
+	span.SetAttributes(config.Attributes()...)
+	t.provider.spans = append(t.provider.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	noop.Span
+	name        string
+	parent      trace.SpanContext
+	context     trace.SpanContext
+	attributes  map[attribute.Key]attribute.Value
+	status      codes.Code
+	description string
+	ended       bool
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.context }
+
+func (s *recordingSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	for _, attr := range attrs {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, description string) {
+	s.status, s.description = code, description
+}
+
+func (s *recordingSpan) End(...trace.SpanEndOption) { s.ended = true }
+
+func TestMiddleware(t *testing.T) {
+	provider := &recordingProvider{}
+	var env map[string]string
+	var spanContext trace.SpanContext
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		env, spanContext = inv.Env, trace.SpanContextFromContext(inv.Context)
+		return &command.ExitStatusError{Code: 4}
+	})
+	factory := commandtrace.NewFactory(inner, commandtrace.Options{TracerProvider: provider, InjectEnv: true})
+	parent := &recordingProvider{}
+	ctx, _ := parent.Tracer("").Start(context.Background(), "request")
+	err := factory.Command(ctx, "/usr/bin/curl", "-u", "admin:secret").
+		With(command.WithSecretArgs(1), command.WithDir("/tmp")).Run()
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	if len(provider.spans) != 1 {
+		t.Fatalf("expected a span, got %d", len(provider.spans))
+	}
+	span := provider.spans[0]
+	if span.name != "exec curl" || !span.ended || !span.parent.Equal(parent.spans[0].context) {
+		t.Errorf("expected an ended child span of the request, got %+v", span)
+	}
+	if !span.context.Equal(spanContext) {
+		t.Error("expected the execution to get the context of the span")
+	}
+	if env["TRACEPARENT"] != "00-01000000000000000000000000000000-0100000000000000-01" {
+		t.Errorf("expected the trace context in the environment, got %v", env)
+	}
+	expected := map[attribute.Key]string{
+		"process.executable.name":   "curl",
+		"process.executable.path":   "/usr/bin/curl",
+		"process.command_args":      `["/usr/bin/curl","-u","***"]`,
+		"process.working_directory": "/tmp",
+		"process.exit.code":         "4",
+	}
+	for key, value := range expected {
+		if emitted := span.attributes[key].Emit(); emitted != value {
+			t.Errorf("expected %s=%s, got %s", key, value, emitted)
+		}
+	}
+	if span.status != codes.Error || span.description == "" {
+		t.Errorf("expected an error status, got %v %q", span.status, span.description)
+	}
+}
+
+func TestMiddlewareSpanName(t *testing.T) {
+	provider := &recordingProvider{}
+	failure := errors.New("cannot start")
+	inner := command.NewFuncFactory(func(*command.Invocation) error { return failure })
+	factory := commandtrace.NewFactory(inner, commandtrace.Options{
+		TracerProvider: provider,
+		SpanName:       func(inv *command.Invocation) string { return inv.CommandLine() },
+	})
+	if err := factory.Command(context.Background(), "git", "fetch").Run(); !errors.Is(err, failure) {
+		t.Fatalf("expected the execution error, got %v", err)
+	}
+	span := provider.spans[0]
+	if span.name != "git fetch" {
+		t.Errorf("expected the custom span name, got %q", span.name)
+	}
+	if _, ok := span.attributes["process.exit.code"]; ok {
+		t.Error("expected no exit code for a command that did not start")
+	}
+	if span.description != "cannot start" {
+		t.Errorf("expected the error as status description, got %q", span.description)
+	}
+}
+
+func TestMiddlewareDurationUsesClock(t *testing.T) {
+	provider := &recordingProvider{}
+	clock := commandtest.NewFakeClock(time.Now())
+	inner := command.NewFuncFactory(func(*command.Invocation) error {
+		clock.Advance(1500 * time.Millisecond)
+		return nil
+	})
+	err := commandtrace.NewFactory(inner, commandtrace.Options{TracerProvider: provider}).
+		Command(context.Background(), "sync").With(command.WithClock(clock)).Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if duration := provider.spans[0].attributes["command.duration_ms"].AsInt64(); duration != 1500 {
+		t.Errorf("expected the duration measured by the clock, got %dms", duration)
+	}
+}
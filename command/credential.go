@@ -0,0 +1,62 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+var ErrPrivilegeRequired = errors.New("insufficient privileges")
+
+type credential struct {
+	uid    uint32
+	gid    uint32
+	groups []uint32
+}
+
+func WithUser(uid, gid uint32) Option {
+	return func(r *commandRequest) {
+		r.credential = &credential{uid: uid, gid: gid}
+	}
+}
+
+// WithUserName runs the command as the named user with its primary and
+// supplementary groups.
+func WithUserName(name string) Option {
+	return func(r *commandRequest) {
+		cred, err := lookupCredential(name)
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.credential = cred
+	}
+}
+
+func lookupCredential(name string) (*credential, error) {
+	account, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve user %q: %w", name, err)
+	}
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has a non numeric uid %q", name, account.Uid)
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has a non numeric gid %q", name, account.Gid)
+	}
+	cred := &credential{uid: uint32(uid), gid: uint32(gid)}
+	groupIds, err := account.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve groups of user %q: %w", name, err)
+	}
+	for _, groupId := range groupIds {
+		group, err := strconv.ParseUint(groupId, 10, 32)
+		if err == nil {
+			cred.groups = append(cred.groups, uint32(group))
+		}
+	}
+	return cred, nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package command
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func applyCredential(*exec.Cmd, *credential) error {
+	return errors.New("running commands as another user is not supported on this platform")
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithUser(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "id", "-u").With(command.WithUser(65534, 65534))
+	output, err := r.RunStdoutStr(command.NewTrimPostModifier(command.PostModifierTrimRight, "\n"))
+	if os.Geteuid() != 0 {
+		if !errors.Is(err, command.ErrPrivilegeRequired) {
+			t.Errorf("expected ErrPrivilegeRequired without root, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "65534" {
+		t.Errorf("expected the command to run as uid 65534, got %s", output)
+	}
+}
+
+func TestWithUserNameReportsUnknownUsers(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "true").With(command.WithUserName("no-such-user-for-tests"))
+	if r.Err() == nil {
+		t.Fatal("expected an error for an unknown user")
+	}
+	if err := r.Run(); err == nil {
+		t.Error("expected Run to fail for an unknown user")
+	}
+}
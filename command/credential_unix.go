@@ -0,0 +1,26 @@
+//go:build unix
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func applyCredential(cmd *exec.Cmd, cred *credential) error {
+	if euid := os.Geteuid(); euid != 0 && uint32(euid) != cred.uid {
+		return fmt.Errorf("%w: running as uid %d, switching to uid %d requires root", ErrPrivilegeRequired, euid, cred.uid)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:         cred.uid,
+		Gid:         cred.gid,
+		Groups:      cred.groups,
+		NoSetGroups: cred.groups == nil && os.Geteuid() != 0,
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithDebugEcho prints the resolved command line to w before running the
+// command, as set -x does: the working directory, the environment given to
+// the command and its arguments, with their secrets redacted.
+func WithDebugEcho(w io.Writer) Option {
+	return func(r *commandRequest) {
+		r.debugEcho = w
+	}
+}
+
+func WithDefaultDebugEcho(w io.Writer) FactoryOption {
+	return WithDefaultOptions(WithDebugEcho(w))
+}
+
+// echo ignores write errors, the output is meant for people and must not
+// fail the command.
+func (r *commandRequest) echo() {
+	if r.debugEcho != nil {
+		fmt.Fprintln(r.debugEcho, "+ "+resolvedCommandLine(r))
+	}
+}
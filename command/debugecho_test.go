@@ -0,0 +1,40 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithDebugEcho(t *testing.T) {
+	var echo bytes.Buffer
+	out, err := command.NewFuncFactory(func(inv *command.Invocation) error {
+		_, err := io.WriteString(inv.Stdout, "done")
+		return err
+	}).Command(context.Background(), "output").With(
+		command.WithDebugEcho(&echo),
+		command.WithDir("/my dir"),
+		command.WithEnv(map[string]string{"TOKEN": "abc", "MODE": "fast"}),
+		command.WithSecretEnv("TOKEN"),
+	).RunStdoutStr()
+	if err != nil || out != "done" {
+		t.Fatalf("expected the command to run, got %q and %v", out, err)
+	}
+	if expected := "+ cd '/my dir' && MODE=fast TOKEN='***' output\n"; echo.String() != expected {
+		t.Errorf("expected %q, got %q", expected, echo.String())
+	}
+}
+
+func TestWithDefaultDebugEcho(t *testing.T) {
+	var echo bytes.Buffer
+	factory := command.NewExecCmdFactory(command.WithDefaultDebugEcho(&echo))
+	if err := factory.Command(context.Background(), "echo", "a b", "s3cret").With(command.WithSecretArgs(1)).Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "+ echo 'a b' '***'\n"; echo.String() != expected {
+		t.Errorf("expected %q, got %q", expected, echo.String())
+	}
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+const maxDecodeSnippetBytes = 256
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+type DecodeError struct {
+	Format  string
+	Snippet string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s output: %v (near %q)", e.Format, e.Err, e.Snippet)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func newDecodeError(format string, output []byte, offset int64, err error) *DecodeError {
+	start := int(offset) - maxDecodeSnippetBytes/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxDecodeSnippetBytes
+	if end > len(output) {
+		end = len(output)
+	}
+	if start > end {
+		start = end
+	}
+	return &DecodeError{Format: format, Snippet: string(output[start:end]), Err: err}
+}
+
+func RunJSON[T any](r Runnable) (T, error) {
+	var value T
+	err := RunJSONInto(r, &value)
+	return value, err
+}
+
+func RunJSONInto(r Runnable, v any) error {
+	output, err := r.RunStdout()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return newDecodeError("json", output, jsonErrorOffset(err), err)
+	}
+	return nil
+}
+
+func jsonErrorOffset(err error) int64 {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Offset
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Offset
+	}
+	return 0
+}
+
+func RunYAML[T any](r Runnable) (T, error) {
+	var value T
+	err := RunYAMLInto(r, &value)
+	return value, err
+}
+
+func RunYAMLInto(r Runnable, v any) error {
+	output, err := r.RunStdout()
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(output, v); err != nil {
+		return newDecodeError("yaml", output, yamlErrorOffset(output, err), err)
+	}
+	return nil
+}
+
+func yamlErrorOffset(output []byte, err error) int64 {
+	match := yamlErrorLineRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	offset := 0
+	for current := 1; current < line; current++ {
+		next := bytes.IndexByte(output[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+	return int64(offset + maxDecodeSnippetBytes/2)
+}
+
+// RunJSONLines decodes output made of one JSON document per line, blank lines
+// are ignored.
+func RunJSONLines[T any](r Runnable) ([]T, error) {
+	output, err := r.RunStdout()
+	if err != nil {
+		return nil, err
+	}
+	values := []T{}
+	offset := 0
+	for lineNumber, line := range bytes.Split(output, []byte("\n")) {
+		lineOffset := offset
+		offset += len(line) + 1
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var value T
+		if err := json.Unmarshal(line, &value); err != nil {
+			return values, newDecodeError("json", output, int64(lineOffset)+jsonErrorOffset(err), fmt.Errorf("line %d: %w", lineNumber+1, err))
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,80 @@
+package command_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+type decodedItem struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func TestRunJSON(t *testing.T) {
+	item, err := command.RunJSON[decodedItem](outputCommand(`{"name": "a", "count": 2}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item != (decodedItem{Name: "a", Count: 2}) {
+		t.Errorf("unexpected value %+v", item)
+	}
+}
+
+func TestRunJSONReportsDecodeError(t *testing.T) {
+	_, err := command.RunJSON[decodedItem](outputCommand(`{"name": "a", "count": "two"}`))
+	var decodeErr *command.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError, got %v", err)
+	}
+	if decodeErr.Format != "json" || !strings.Contains(decodeErr.Snippet, `"two"`) {
+		t.Errorf("unexpected decode error %+v", decodeErr)
+	}
+}
+
+func TestRunYAML(t *testing.T) {
+	items, err := command.RunYAML[[]decodedItem](outputCommand("- name: a\n  count: 1\n- name: b\n  count: 2\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[1] != (decodedItem{Name: "b", Count: 2}) {
+		t.Errorf("unexpected value %+v", items)
+	}
+}
+
+func TestRunYAMLReportsDecodeError(t *testing.T) {
+	_, err := command.RunYAML[[]decodedItem](outputCommand("- name: a\n  count: [\n"))
+	var decodeErr *command.DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Format != "yaml" {
+		t.Fatalf("expected a yaml DecodeError, got %v", err)
+	}
+	if !strings.Contains(decodeErr.Snippet, "count: [") {
+		t.Errorf("expected the snippet to show the failing line, got %q", decodeErr.Snippet)
+	}
+}
+
+func TestRunJSONLines(t *testing.T) {
+	items, err := command.RunJSONLines[decodedItem](outputCommand("{\"name\": \"a\", \"count\": 1}\n\n{\"name\": \"b\", \"count\": 2}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0] != (decodedItem{Name: "a", Count: 1}) || items[1] != (decodedItem{Name: "b", Count: 2}) {
+		t.Errorf("unexpected values %+v", items)
+	}
+}
+
+func TestRunJSONLinesReportsDecodeError(t *testing.T) {
+	items, err := command.RunJSONLines[decodedItem](outputCommand("{\"name\": \"a\"}\n{\"name\": \"b\", \"count\": \"two\"}\n"))
+	var decodeErr *command.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 2") || !strings.Contains(decodeErr.Snippet, `"two"`) {
+		t.Errorf("unexpected decode error %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("expected the values decoded before the failure, got %+v", items)
+	}
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WithDefaultOptions applies opts to every command created by the factory,
+// before any option given to the command itself.
+func WithDefaultOptions(opts ...Option) FactoryOption {
+	return func(f *execCmdFactory) {
+		f.defaults = append(f.defaults, opts...)
+	}
+}
+
+func WithDefaultEnv(env map[string]string) FactoryOption {
+	return WithDefaultOptions(WithEnv(env))
+}
+
+func WithDefaultDir(dir string) FactoryOption {
+	return WithDefaultOptions(WithDir(dir))
+}
+
+func WithDefaultTimeout(timeout time.Duration) FactoryOption {
+	return WithDefaultOptions(WithTimeout(timeout))
+}
+
+// WithPathPrepend adds dirs in front of the PATH of the commands, which is
+// also used to find their executables.
+func WithPathPrepend(dirs ...string) FactoryOption {
+	return WithDefaultOptions(func(r *commandRequest) {
+		r.pathPrepend = append(r.pathPrepend, dirs...)
+	})
+}
+
+// searchPath returns the PATH of the command when it differs from the one of
+// the current process.
+func (r *commandRequest) searchPath() (string, bool) {
+	if len(r.pathPrepend) == 0 {
+		return "", false
+	}
+	path, ok := r.env["PATH"]
+	if !ok {
+		if path, ok = r.fileEnv["PATH"]; !ok && !r.noEnvInherit {
+			path = os.Getenv("PATH")
+		}
+	}
+	dirs := append([]string(nil), r.pathPrepend...)
+	if path != "" {
+		dirs = append(dirs, path)
+	}
+	return strings.Join(dirs, string(os.PathListSeparator)), true
+}
+
+func (r *commandRequest) lookPath() (string, error) {
+	path, ok := r.searchPath()
+	if !ok {
+		return exec.LookPath(r.cmd)
+	}
+	return r.lookPathIn(path)
+}
+
+// lookPathIn finds the executable in path, the search path of the command.
+func (r *commandRequest) lookPathIn(path string) (string, error) {
+	if strings.ContainsAny(r.cmd, `/\`) {
+		return exec.LookPath(r.cmd)
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		if executable, err := exec.LookPath(filepath.Join(dir, r.cmd)); err == nil {
+			return executable, nil
+		}
+	}
+	return "", &exec.Error{Name: r.cmd, Err: exec.ErrNotFound}
+}
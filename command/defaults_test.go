@@ -0,0 +1,58 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestFactoryDefaults(t *testing.T) {
+	dir := t.TempDir()
+	factory := command.NewExecCmdFactory(
+		command.WithDefaultEnv(map[string]string{"A": "default", "B": "default"}),
+		command.WithDefaultDir(dir),
+	)
+	out, err := factory.Command(context.Background(), "sh", "-c", `echo "$A $B"; pwd -P`).
+		With(command.WithEnv(map[string]string{"B": "command"})).
+		RunLines()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(dir)
+	if len(out) != 2 || out[0] != "default command" || out[1] != resolved {
+		t.Errorf("expected the defaults overridden by the command options, got %q", out)
+	}
+}
+
+func TestFactoryDefaultTimeout(t *testing.T) {
+	err := command.NewExecCmdFactory(command.WithDefaultTimeout(50*time.Millisecond)).
+		Command(context.Background(), "sleep", "10").Run()
+	var timeoutErr *command.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Errorf("expected a TimeoutError, got %v", err)
+	}
+}
+
+func TestWithPathPrepend(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "only-in-prepended-dir"), []byte("#!/bin/sh\necho \"$PATH\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := command.NewExecCmdFactory(command.WithPathPrepend(dir)).
+		Command(context.Background(), "only-in-prepended-dir").
+		RunStdoutStr(command.NewTrimPostModifier(command.PostModifierTrimRight, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out, dir+string(os.PathListSeparator)) {
+		t.Errorf("expected the directory in front of PATH, got %q", out)
+	}
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type DryRunFactory struct {
+	mu       sync.Mutex
+	sink     io.Writer
+	recorded []string
+}
+
+func NewDryRunFactory(sink io.Writer) *DryRunFactory {
+	return &DryRunFactory{sink: sink}
+}
+
+func (f *DryRunFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return &execCommand{commandRequest: commandRequest{ctx: ctx, cmd: cmd, args: slices.Clone(args), executor: f.record}}
+}
+
+func (f *DryRunFactory) Recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.recorded...)
+}
+
+func (f *DryRunFactory) record(req *commandRequest, _ *streams) error {
+	line := resolvedCommandLine(req)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recorded = append(f.recorded, line)
+	if f.sink != nil {
+		if _, err := fmt.Fprintln(f.sink, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolvedCommandLine(req *commandRequest) string {
+	var parts []string
+	if req.dir != "" {
+		parts = append(parts, "cd", Quote(req.dir), "&&")
+	}
+	if req.noEnvInherit {
+		parts = append(parts, "env", "-i")
+	}
+	env := req.mergedEnv()
+	if path, ok := req.searchPath(); ok {
+		env["PATH"] = path
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, key+"="+Quote(req.redactedEnv(key, env[key])))
+	}
+	parts = append(parts, Join(append([]string{req.cmd}, req.redactedArgs()...)...))
+	return strings.Join(parts, " ")
+}
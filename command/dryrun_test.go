@@ -0,0 +1,36 @@
+package command_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestDryRunFactory(t *testing.T) {
+	var sink strings.Builder
+	factory := command.NewDryRunFactory(&sink)
+	ctx := context.Background()
+	if err := factory.Command(ctx, "rm", "-rf", "dist").Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output, err := factory.Command(ctx, "make", "build").
+		With(command.WithDir("/src"), command.WithEnv(map[string]string{"B": "2", "A": "1"})).
+		RunStdoutStr()
+	if err != nil || output != "" {
+		t.Fatalf("expected an empty output, got %q and %v", output, err)
+	}
+
+	expected := []string{
+		`rm -rf dist`,
+		`cd /src && A=1 B=2 make build`,
+	}
+	if recorded := factory.Recorded(); !slices.Equal(recorded, expected) {
+		t.Errorf("expected %q, got %q", expected, recorded)
+	}
+	if sink.String() != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("unexpected sink content %q", sink.String())
+	}
+}
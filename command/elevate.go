@@ -0,0 +1,128 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+type ElevateMethod int
+
+const (
+	Sudo ElevateMethod = iota
+	Doas
+	Runas
+)
+
+type ElevateOptions struct {
+	Method ElevateMethod
+	// User to run as, the escalation tool default (usually root) when empty.
+	User string
+	// NonInteractive makes the command fail instead of prompting for a
+	// password.
+	NonInteractive bool
+	// AskPassHelper is a program printing the password, only supported by
+	// sudo.
+	AskPassHelper string
+	// Password is handed to sudo, doas and runas only reading passwords from
+	// a terminal. With an AskPassHelper it is given to the helper in the
+	// ASKPASS_PASSWORD environment variable, which sudo drops from the
+	// command environment. Otherwise sudo reads it from its stdin, ahead of
+	// the input of the command, and is run with -k so a cached credential
+	// does not leave the password to the command. Hosts with NOPASSWD rules
+	// should use an AskPassHelper, as sudo does not prompt there and the
+	// command would read the password.
+	Password string
+}
+
+const askPassPasswordEnv = "ASKPASS_PASSWORD"
+
+type elevatedFactory struct {
+	factory CommandFactory
+	opts    ElevateOptions
+}
+
+// Elevate returns a factory running the commands of factory through the
+// escalation tool selected in opts.
+func Elevate(factory CommandFactory, opts ElevateOptions) CommandFactory {
+	return &elevatedFactory{factory: factory, opts: opts}
+}
+
+func (f *elevatedFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	var wrapper []string
+	options := []Option{withUserArgs(args)}
+	switch f.opts.Method {
+	case Doas:
+		wrapper = append(wrapper, "doas")
+		if f.opts.NonInteractive {
+			wrapper = append(wrapper, "-n")
+		}
+		if f.opts.User != "" {
+			wrapper = append(wrapper, "-u", f.opts.User)
+		}
+		if f.opts.AskPassHelper != "" || f.opts.Password != "" {
+			options = append(options, withError(errors.New("doas only reads passwords from a terminal")))
+		}
+		wrapper = append(wrapper, "--", cmd)
+		wrapper = append(wrapper, args...)
+	case Runas:
+		user := f.opts.User
+		if user == "" {
+			user = "Administrator"
+		}
+		wrapper = append(wrapper, "runas", "/user:"+user, JoinWindows(append([]string{cmd}, args...)...))
+		if f.opts.AskPassHelper != "" || f.opts.Password != "" {
+			options = append(options, withError(errors.New("runas only reads passwords from a console")))
+		}
+	default:
+		wrapper = append(wrapper, "sudo")
+		if f.opts.NonInteractive {
+			wrapper = append(wrapper, "-n")
+		}
+		if f.opts.User != "" {
+			wrapper = append(wrapper, "-u", f.opts.User)
+		}
+		if f.opts.AskPassHelper != "" {
+			wrapper = append(wrapper, "-A")
+			env := map[string]string{"SUDO_ASKPASS": f.opts.AskPassHelper}
+			if f.opts.Password != "" {
+				env[askPassPasswordEnv] = f.opts.Password
+				options = append(options, WithSecretEnv(askPassPasswordEnv))
+			}
+			options = append(options, WithEnv(env))
+		} else if f.opts.Password != "" {
+			if f.opts.NonInteractive {
+				options = append(options, withError(errors.New("non-interactive sudo cannot read a password from stdin")))
+			}
+			wrapper = append(wrapper, "-S", "-k", "-p", "")
+			options = append(options, withStdinPrefix(f.opts.Password+"\n"))
+		}
+		wrapper = append(wrapper, "--", cmd)
+		wrapper = append(wrapper, args...)
+	}
+	return f.factory.Command(ctx, wrapper[0], wrapper[1:]...).With(options...)
+}
+
+// withStdinPrefix writes prefix to the stdin of the command ahead of its own
+// input.
+func withStdinPrefix(prefix string) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			stdin := s.stdin
+			defer func() { s.stdin = stdin }()
+			if stdin == nil {
+				s.stdin = strings.NewReader(prefix)
+			} else {
+				s.stdin = io.MultiReader(strings.NewReader(prefix), stdin)
+			}
+			return next(req, s)
+		}
+	})
+}
+
+func withError(err error) Option {
+	return func(r *commandRequest) {
+		r.err = err
+	}
+}
@@ -0,0 +1,75 @@
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestElevateSecretArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     command.ElevateOptions
+		expected string
+	}{
+		{name: "sudo", opts: command.ElevateOptions{User: "x"}, expected: "sudo -u x -- mysql '***' db"},
+		{name: "doas", opts: command.ElevateOptions{Method: command.Doas, User: "x"}, expected: "doas -u x -- mysql '***' db"},
+		{name: "runas", opts: command.ElevateOptions{Method: command.Runas, User: "x"}, expected: "runas /user:x 'mysql *** db'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := command.NewDryRunFactory(nil)
+			err := command.Elevate(recorder, test.opts).Command(context.Background(), "mysql", "-pSECRET", "db").With(command.WithSecretArgs(0)).Run()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if recorded := recorder.Recorded(); len(recorded) != 1 || recorded[0] != test.expected {
+				t.Errorf("expected %s, got %v", test.expected, recorded)
+			}
+		})
+	}
+}
+
+// catFactory runs cat in place of its commands, reporting their command lines.
+type catFactory func(cmdLine string)
+
+func (f catFactory) Command(ctx context.Context, cmd string, args ...string) command.Runnable {
+	f(command.Join(append([]string{cmd}, args...)...))
+	return command.NewExecCmdFactory().Command(ctx, "cat")
+}
+
+func TestElevateSudoPasswordOnStdin(t *testing.T) {
+	var line string
+	inner := catFactory(func(cmdLine string) { line = cmdLine })
+	stdin, err := command.Elevate(inner, command.ElevateOptions{Password: "pw"}).Command(context.Background(), "tee", "/etc/motd").
+		RunWithInputStr("hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if line != "sudo -S -k -p '' -- tee /etc/motd" {
+		t.Errorf("expected sudo to read the password from stdin, got %s", line)
+	}
+	if stdin != "pw\nhello" {
+		t.Errorf("expected the password ahead of the input, got %q", stdin)
+	}
+}
+
+func TestElevatePasswords(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    command.ElevateOptions
+		success bool
+	}{
+		{name: "askpass", opts: command.ElevateOptions{AskPassHelper: "/bin/askpass", Password: "pw"}, success: true},
+		{name: "non-interactive", opts: command.ElevateOptions{NonInteractive: true, Password: "pw"}},
+		{name: "doas", opts: command.ElevateOptions{Method: command.Doas, Password: "pw"}},
+		{name: "runas", opts: command.ElevateOptions{Method: command.Runas, Password: "pw"}},
+	}
+	for _, test := range tests {
+		err := command.Elevate(command.NewDryRunFactory(nil), test.opts).Command(context.Background(), "id").Run()
+		if (err == nil) != test.success {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// WithEnvFile loads dotenv style files into the command environment. Later
+// files override earlier ones and values set with WithEnv always take
+// precedence over file values, which in turn override the inherited
+// environment. References like $VAR or ${VAR} in unquoted and double quoted
+// values expand to previously defined file values or the current process
+// environment.
+func WithEnvFile(paths ...string) Option {
+	return func(r *commandRequest) {
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				r.err = fmt.Errorf("failed to read env file: %w", err)
+				return
+			}
+			if r.fileEnv == nil {
+				r.fileEnv = make(map[string]string)
+			}
+			if err := parseEnvFile(content, r.fileEnv); err != nil {
+				r.err = fmt.Errorf("failed to parse env file %s: %w", path, err)
+				return
+			}
+		}
+	}
+}
+
+func parseEnvFile(content []byte, env map[string]string) error {
+	lookup := func(key string) string {
+		if value, ok := env[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated single quote", lineNumber)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			unquoted, ok := unquoteEnvValue(value[1:], lookup)
+			if !ok {
+				return fmt.Errorf("line %d: unterminated double quote", lineNumber)
+			}
+			value = unquoted
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+			value = os.Expand(value, lookup)
+		}
+		env[key] = value
+	}
+	return scanner.Err()
+}
+
+// unquoteEnvValue reads a double quoted value up to its closing quote,
+// handling escapes and expanding references with lookup, when given, in the
+// same pass so an escaped dollar stays literal.
+func unquoteEnvValue(value string, lookup func(string) string) (string, bool) {
+	var unquoted strings.Builder
+	for index := 0; index < len(value); index++ {
+		switch char := value[index]; char {
+		case '"':
+			return unquoted.String(), true
+		case '$':
+			if length := envReferenceLength(value[index:]); lookup != nil && length > 0 {
+				unquoted.WriteString(os.Expand(value[index:index+length], lookup))
+				index += length - 1
+			} else {
+				unquoted.WriteByte(char)
+			}
+		case '\\':
+			if index+1 == len(value) {
+				return "", false
+			}
+			index++
+			switch escaped := value[index]; escaped {
+			case 'n':
+				unquoted.WriteByte('\n')
+			case 't':
+				unquoted.WriteByte('\t')
+			case 'r':
+				unquoted.WriteByte('\r')
+			default:
+				unquoted.WriteByte(escaped)
+			}
+		default:
+			unquoted.WriteByte(char)
+		}
+	}
+	return "", false
+}
+
+// envReferenceLength returns the length of the $VAR or ${VAR} reference at
+// the start of value, zero when there is none.
+func envReferenceLength(value string) int {
+	if strings.HasPrefix(value, "${") {
+		if end := strings.IndexAny(value, `}"`); end >= 0 && value[end] == '}' {
+			return end + 1
+		}
+		return 0
+	}
+	length := 1
+	for length < len(value) && isEnvNameChar(value[length]) {
+		length++
+	}
+	if length == 1 {
+		return 0
+	}
+	return length
+}
+
+func isEnvNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,88 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func writeEnvFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func envOf(t *testing.T, opts ...command.Option) map[string]string {
+	t.Helper()
+	lines, err := command.NewExecCmdFactory().Command(context.Background(), "env").With(opts...).RunLines()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env := map[string]string{}
+	for _, line := range lines {
+		key, value, _ := strings.Cut(line, "=")
+		env[key] = value
+	}
+	return env
+}
+
+func TestWithEnvFile(t *testing.T) {
+	t.Setenv("COMMAND_TEST_HOST", "example.com")
+	path := writeEnvFile(t, `
+A=one
+ESCAPED="\$A"
+BRACED="${A}-$A"
+NESTED="x${COMMAND_TEST_HOST}y"
+QUOTE="say \"hi\" \\ $A"
+DOLLAR="cost $ 5 $"
+SINGLE='$A'
+UNQUOTED=$A # comment
+`)
+	env := envOf(t, command.WithEnvFile(path))
+	expected := map[string]string{
+		"A":        "one",
+		"ESCAPED":  "$A",
+		"BRACED":   "one-one",
+		"NESTED":   "xexample.comy",
+		"QUOTE":    `say "hi" \ one`,
+		"DOLLAR":   "cost $ 5 $",
+		"SINGLE":   "$A",
+		"UNQUOTED": "one",
+	}
+	for key, value := range expected {
+		if env[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, env[key])
+		}
+	}
+}
+
+func TestWithEnvFilePrecedence(t *testing.T) {
+	t.Setenv("COMMAND_TEST_INHERITED", "inherited")
+	first := writeEnvFile(t, "COMMAND_TEST_INHERITED=first\nB=first\nC=first\n")
+	second := writeEnvFile(t, "B=second\nC=second\n")
+	env := envOf(t, command.WithEnvFile(first, second), command.WithEnv(map[string]string{"C": "explicit"}))
+	if env["COMMAND_TEST_INHERITED"] != "first" || env["B"] != "second" || env["C"] != "explicit" {
+		t.Errorf("unexpected precedence: %v", env)
+	}
+}
+
+func TestWithEnvFileErrors(t *testing.T) {
+	for name, path := range map[string]string{
+		"missing":   filepath.Join(t.TempDir(), "missing.env"),
+		"malformed": writeEnvFile(t, "NOT A VALUE\n"),
+	} {
+		err := command.NewExecCmdFactory().Command(context.Background(), "true").With(command.WithEnvFile(path)).Run()
+		if err == nil || !strings.Contains(err.Error(), "env file") {
+			t.Errorf("%s: expected an env file error, got %v", name, err)
+		}
+	}
+}
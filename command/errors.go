@@ -0,0 +1,206 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var ErrExecutableNotFound = errors.New("executable not found")
+
+var errCommandTimeout = errors.New("command timeout")
+
+type TimeoutError struct {
+	Timeout time.Duration
+	// Idle is set when the command was stopped for not producing output.
+	Idle bool
+	Err  error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Idle {
+		return fmt.Sprintf("command produced no output for %s: %v", e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("command timed out after %s: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// canceledError is the error of a command stopped because its context is
+// done, which keeps the message of the process error.
+type canceledError struct {
+	cause error
+	err   error
+}
+
+func (e *canceledError) Error() string {
+	return e.err.Error()
+}
+
+func (e *canceledError) Unwrap() []error {
+	return []error{e.err, e.cause}
+}
+
+type CommandError struct {
+	cmd         string
+	args        []string
+	dir         string
+	exitCode    int
+	stderr      []byte
+	duration    time.Duration
+	err         error
+	rich        bool
+	stderrLines int
+}
+
+func newCommandError(req *commandRequest, err error, stderr []byte, duration time.Duration) *CommandError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	var statusErr *ExitStatusError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if errors.As(err, &statusErr) {
+		exitCode = statusErr.Code
+	}
+	return &CommandError{
+		cmd:         req.cmd,
+		args:        req.redactedArgs(),
+		dir:         req.dir,
+		exitCode:    exitCode,
+		stderr:      req.redact(stderr),
+		duration:    duration,
+		err:         err,
+		rich:        req.richErrors,
+		stderrLines: req.errorStderrLines,
+	}
+}
+
+// WithRichErrors makes the errors of the commands describe the execution:
+// the command line, with its secrets redacted, the working directory, the
+// exit code and the last stderrLines lines written to stderr. Without it
+// the message is the one of the underlying error, like "exit status 1".
+func WithRichErrors(stderrLines int) FactoryOption {
+	return WithDefaultOptions(func(r *commandRequest) {
+		r.errorStderrLines = max(stderrLines, 0)
+		r.richErrors = true
+	})
+}
+
+func (e *CommandError) Error() string {
+	if !e.rich {
+		return e.err.Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "command %q failed", e.CommandLine())
+	if e.dir != "" {
+		fmt.Fprintf(&b, " in %s", e.dir)
+	}
+	if e.exitCode >= 0 {
+		fmt.Fprintf(&b, " with exit code %d", e.exitCode)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.err.Error())
+	if lines := lastLines(e.stderr, e.stderrLines); len(lines) > 0 {
+		b.WriteString(": stderr: ")
+		b.WriteString(strings.Join(lines, " | "))
+	}
+	return b.String()
+}
+
+// lastLines returns the last n non blank lines of text.
+func lastLines(text []byte, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(string(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines[max(len(lines)-n, 0):]
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.err
+}
+
+func (e *CommandError) Command() string {
+	return e.cmd
+}
+
+// Args returns the command arguments with the ones marked as secret redacted.
+func (e *CommandError) Args() []string {
+	return e.args
+}
+
+func (e *CommandError) CommandLine() string {
+	return Join(append([]string{e.cmd}, e.args...)...)
+}
+
+// ExitCode returns -1 when the command did not exit on its own, for example
+// when it could not be started or was killed.
+func (e *CommandError) ExitCode() int {
+	return e.exitCode
+}
+
+func (e *CommandError) Stderr() []byte {
+	return e.stderr
+}
+
+func (e *CommandError) Duration() time.Duration {
+	return e.duration
+}
+
+// IsNotFound reports whether err comes from a missing executable, or a
+// missing file or directory needed to start it.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrExecutableNotFound) || errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// IsPermission reports whether err comes from missing permissions or
+// privileges, such as running a file that is not executable.
+func IsPermission(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, ErrPrivilegeRequired)
+}
+
+// IsTimeout reports whether the command was stopped by WithTimeout,
+// WithIdleTimeout or a context deadline.
+func IsTimeout(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsCanceled reports whether the command was stopped by the cancellation of
+// its context.
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// ExitCode returns the exit code of a command that exited on its own, zero for
+// a nil error. It returns false when the command did not run to its end.
+func ExitCode(err error) (int, bool) {
+	if err == nil {
+		return 0, true
+	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode(), cmdErr.ExitCode() >= 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), exitErr.ExitCode() >= 0
+	}
+	var statusErr *ExitStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code, true
+	}
+	return -1, false
+}
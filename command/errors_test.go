@@ -0,0 +1,126 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestRunReportsCommandError(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo broken >&2; exit 3")
+	_, err := r.RunStdoutStr()
+
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected a CommandError, got %v", err)
+	}
+	if cmdErr.ExitCode() != 3 {
+		t.Errorf("expected exit code 3, got %d", cmdErr.ExitCode())
+	}
+	if stderr := string(cmdErr.Stderr()); stderr != "broken\n" {
+		t.Errorf("unexpected stderr %q", stderr)
+	}
+	if cmdErr.Command() != "sh" || !strings.HasPrefix(cmdErr.CommandLine(), "sh -c ") {
+		t.Errorf("unexpected command line %q", cmdErr.CommandLine())
+	}
+}
+
+func TestRunReportsCommandErrorForInvalidCommands(t *testing.T) {
+	r := command.NewExecCmdFactory(command.WithLookPath()).Command(context.Background(), "no-such-command-in-path")
+	if !errors.Is(r.Err(), command.ErrExecutableNotFound) {
+		t.Fatalf("expected ErrExecutableNotFound, got %v", r.Err())
+	}
+	for name, run := range map[string]func() error{
+		"Run":          r.Run,
+		"RunStdoutStr": func() error { _, err := r.RunStdoutStr(); return err },
+		"RunCombined":  func() error { _, err := r.RunCombined(); return err },
+	} {
+		err := run()
+		var cmdErr *command.CommandError
+		if !errors.As(err, &cmdErr) {
+			t.Errorf("%s: expected a CommandError, got %v", name, err)
+			continue
+		}
+		if !errors.Is(err, command.ErrExecutableNotFound) || cmdErr.ExitCode() != -1 {
+			t.Errorf("%s: unexpected error %v with exit code %d", name, err, cmdErr.ExitCode())
+		}
+	}
+}
+
+func TestRunReportsCommandErrorForConsumedStdin(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "cat").
+		With(command.WithStdinReader(strings.NewReader("input")))
+	if err := r.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := r.Run()
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || !errors.Is(err, command.ErrStdinConsumed) {
+		t.Errorf("expected a CommandError wrapping ErrStdinConsumed, got %v", err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	factory := command.NewExecCmdFactory()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	notFound := command.NewExecCmdFactory(command.WithLookPath()).Command(context.Background(), "no-such-command-in-path").Run()
+	if !command.IsNotFound(notFound) || command.IsPermission(notFound) {
+		t.Errorf("expected a not found error, got %v", notFound)
+	}
+	if err := factory.Command(context.Background(), script).Run(); !command.IsPermission(err) || command.IsNotFound(err) {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+	timeout := factory.Command(context.Background(), "sleep", "5").With(command.WithTimeout(10 * time.Millisecond)).Run()
+	if !command.IsTimeout(timeout) || command.IsCanceled(timeout) {
+		t.Errorf("expected a timeout, got %v", timeout)
+	}
+	if _, ok := command.ExitCode(timeout); ok {
+		t.Errorf("expected no exit code for a stopped command, got %v", timeout)
+	}
+	if err := factory.Command(canceled, "sleep", "5").Run(); !command.IsCanceled(err) || command.IsTimeout(err) {
+		t.Errorf("expected a cancellation, got %v", err)
+	}
+	exit := factory.Command(context.Background(), "sh", "-c", "exit 7").Run()
+	if code, ok := command.ExitCode(exit); !ok || code != 7 {
+		t.Errorf("expected exit code 7, got %d from %v", code, exit)
+	}
+	if code, ok := command.ExitCode(nil); !ok || code != 0 {
+		t.Errorf("expected exit code 0 for success, got %d", code)
+	}
+	if code, ok := command.ExitCode(errors.New("other")); ok || code != -1 {
+		t.Errorf("expected no exit code for unrelated errors, got %d", code)
+	}
+}
+
+func TestWithRichErrors(t *testing.T) {
+	dir := t.TempDir()
+	factory := command.NewExecCmdFactory(command.WithRichErrors(2), command.WithDefaultDir(dir))
+	err := factory.Command(context.Background(), "sh", "-c", "exec >&2; echo one; echo two; echo; echo three; exit 4", "s3cret").
+		With(command.WithSecretArgs(2)).Run()
+	expected := `command "sh -c 'exec >&2; echo one; echo two; echo; echo three; exit 4' '***'" failed in ` + dir +
+		` with exit code 4: exit status 4: stderr: two | three`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %s, got %v", expected, err)
+	}
+	if code, ok := command.ExitCode(err); !ok || code != 4 {
+		t.Errorf("expected the exit code to still be available, got %v", err)
+	}
+	plain := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo broken >&2; exit 4").Run()
+	if plain == nil || plain.Error() != "exit status 4" {
+		t.Errorf("expected the bare error without rich errors, got %v", plain)
+	}
+}
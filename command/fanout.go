@@ -0,0 +1,130 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type SlowWriterPolicy int
+
+const (
+	// SlowWriterBlock waits for a writer whose buffer is full.
+	SlowWriterBlock SlowWriterPolicy = iota
+	// SlowWriterDrop discards the writes a full writer has no room for.
+	SlowWriterDrop
+)
+
+const defaultFanOutBuffer = 64
+
+type FanOutOptions struct {
+	// Buffer is the number of writes queued per writer, 64 when zero.
+	Buffer int
+	Policy SlowWriterPolicy
+}
+
+// FanOutWriter copies writes to several writers, each one fed by its own
+// goroutine so a slow writer does not delay the others or the command. It is
+// safe for concurrent use, so it can be given as both stdout and stderr.
+// Writer errors are returned by Close, a failed writer being skipped from then
+// on.
+type FanOutWriter struct {
+	mu      sync.Mutex
+	closed  bool
+	sinks   []*fanOutSink
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+	policy  SlowWriterPolicy
+}
+
+type fanOutSink struct {
+	writer io.Writer
+	queue  chan []byte
+	err    error
+}
+
+// MultiWriter returns a FanOutWriter with the default options.
+func MultiWriter(writers ...io.Writer) *FanOutWriter {
+	return NewFanOutWriter(FanOutOptions{}, writers...)
+}
+
+func NewFanOutWriter(opts FanOutOptions, writers ...io.Writer) *FanOutWriter {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = defaultFanOutBuffer
+	}
+	w := &FanOutWriter{policy: opts.Policy}
+	for _, writer := range writers {
+		sink := &fanOutSink{writer: writer, queue: make(chan []byte, buffer)}
+		w.sinks = append(w.sinks, sink)
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			sink.run()
+		}()
+	}
+	return w
+}
+
+func (s *fanOutSink) run() {
+	for p := range s.queue {
+		if s.err != nil {
+			continue
+		}
+		if _, err := s.writer.Write(p); err != nil {
+			s.err = err
+		}
+	}
+}
+
+func (w *FanOutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	chunk := append([]byte(nil), p...)
+	for _, sink := range w.sinks {
+		if w.policy == SlowWriterBlock {
+			sink.queue <- chunk
+			continue
+		}
+		select {
+		case sink.queue <- chunk:
+		default:
+			w.dropped.Add(1)
+		}
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many writes were discarded by SlowWriterDrop, counting
+// once per writer.
+func (w *FanOutWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Close waits for the queued writes to complete and returns the writer errors.
+func (w *FanOutWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		for _, sink := range w.sinks {
+			close(sink.queue)
+		}
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	var errs []error
+	for index, sink := range w.sinks {
+		if sink.err != nil {
+			errs = append(errs, fmt.Errorf("writer %d failed: %w", index, sink.err))
+		}
+	}
+	return errors.Join(errs...)
+}
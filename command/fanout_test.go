@@ -0,0 +1,80 @@
+package command_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+// blockedWriter blocks its writes until release is closed.
+type blockedWriter struct {
+	release chan struct{}
+	buffer  bytes.Buffer
+}
+
+func (w *blockedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.buffer.Write(p)
+}
+
+func TestMultiWriter(t *testing.T) {
+	var first, second bytes.Buffer
+	writer := command.MultiWriter(&first, &second)
+	if err := streamsCommand("out\n", "!err\n").RunToWriter(writer, writer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.String() != "out\nerr\n" || second.String() != "out\nerr\n" {
+		t.Errorf("expected both writers to get the output, got %q and %q", first.String(), second.String())
+	}
+	if _, err := writer.Write([]byte("late")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("expected writes after Close to fail, got %v", err)
+	}
+}
+
+func TestFanOutWriterReportsFailedWriters(t *testing.T) {
+	failure := errors.New("disk full")
+	var kept bytes.Buffer
+	writer := command.MultiWriter(failingWriter{err: failure}, &kept)
+	for range 2 {
+		if _, err := writer.Write([]byte("a")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := writer.Close(); !errors.Is(err, failure) {
+		t.Errorf("expected the writer failure, got %v", err)
+	}
+	if kept.String() != "aa" {
+		t.Errorf("expected the other writer to keep going, got %q", kept.String())
+	}
+}
+
+func TestFanOutWriterDropsForSlowWriters(t *testing.T) {
+	slow := &blockedWriter{release: make(chan struct{})}
+	writer := command.NewFanOutWriter(command.FanOutOptions{Buffer: 1, Policy: command.SlowWriterDrop}, slow)
+	for range 5 {
+		if _, err := writer.Write([]byte("x")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	close(slow.release)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if writer.Dropped() == 0 || int64(slow.buffer.Len())+writer.Dropped() != 5 {
+		t.Errorf("expected the writes the slow writer had no room for to be dropped, got %q and %d dropped", slow.buffer.String(), writer.Dropped())
+	}
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"strings"
+)
+
+// Fields returns the n-th whitespace separated field of every line of output,
+// counting from 1 like awk or from the end with negative values, -1 being
+// the last field. Lines without such field give an empty string.
+func Fields(output string, n int) []string {
+	lines, _ := splitLines(output)
+	fields := make([]string, len(lines))
+	for index, line := range lines {
+		fields[index] = field(line, n)
+	}
+	return fields
+}
+
+func field(line string, n int) string {
+	fields := strings.Fields(line)
+	if n < 0 {
+		n += len(fields) + 1
+	}
+	if n < 1 || n > len(fields) {
+		return ""
+	}
+	return fields[n-1]
+}
+
+type fieldPostModifier struct {
+	field int
+}
+
+// NewFieldPostModifier replaces each line of the output by its n-th field, as
+// Fields does.
+func NewFieldPostModifier(n int) *fieldPostModifier {
+	return &fieldPostModifier{field: n}
+}
+
+func (m *fieldPostModifier) process(content string) (string, error) {
+	_, trailing := splitLines(content)
+	return joinLines(Fields(content, m.field), trailing), nil
+}
@@ -0,0 +1,31 @@
+package command_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestFields(t *testing.T) {
+	output := "root  1  /sbin/init\nuser\t42 bash\nlonely\n"
+	tests := map[int][]string{
+		1:  {"root", "user", "lonely"},
+		2:  {"1", "42", ""},
+		-1: {"/sbin/init", "bash", "lonely"},
+		-3: {"root", "user", ""},
+		0:  {"", "", ""},
+	}
+	for n, expected := range tests {
+		if fields := command.Fields(output, n); !slices.Equal(fields, expected) {
+			t.Errorf("%d: expected %q, got %q", n, expected, fields)
+		}
+	}
+}
+
+func TestFieldPostModifier(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "trailing newline", output: "a 1\nb 2\n", modifier: command.NewFieldPostModifier(2), expected: "1\n2\n"},
+		{name: "last", output: "a 1\nb 2", modifier: command.NewFieldPostModifier(-1), expected: "1\n2"},
+	})
+}
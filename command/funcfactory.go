@@ -0,0 +1,148 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Invocation is an execution as seen by a Middleware or by the function of a
+// factory created with NewFuncFactory. Streams are nil in middlewares when the
+// command does not use them, while NewFuncFactory functions always get them.
+type Invocation struct {
+	Context context.Context
+	Cmd     string
+	Args    []string
+	Dir     string
+	// Env holds the variables set on the command, not the inherited ones.
+	Env    map[string]string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	secretArgs []string
+	secretEnv  []string
+	clock      Clock
+}
+
+func newInvocation(req *commandRequest, s *streams) *Invocation {
+	return &Invocation{
+		Context:    req.ctx,
+		Cmd:        req.cmd,
+		Args:       append([]string(nil), req.args...),
+		Dir:        req.dir,
+		Env:        req.mergedEnv(),
+		Stdin:      s.stdin,
+		Stdout:     s.stdout,
+		Stderr:     s.stderr,
+		secretArgs: req.secretArgs,
+		secretEnv:  req.secretEnv,
+		clock:      req.clockOrDefault(),
+	}
+}
+
+// Clock returns the clock the command runs with, see WithClock.
+func (i *Invocation) Clock() Clock {
+	if i.clock == nil {
+		return systemClock{}
+	}
+	return i.clock
+}
+
+func (i *Invocation) CommandLine() string {
+	return Join(append([]string{i.Cmd}, i.Args...)...)
+}
+
+// CombinedOutput reports whether Stdout and Stderr are the same writer, as
+// for RunCombined, so code wrapping the streams can keep the output combined.
+func (i *Invocation) CombinedOutput() bool {
+	return sameWriter(i.Stdout, i.Stderr)
+}
+
+// RedactedArgs returns the arguments with the ones marked as secret by
+// WithSecretArgs replaced by ***.
+func (i *Invocation) RedactedArgs() []string {
+	return i.secrets().redactedArgs()
+}
+
+// Redact replaces the values of the secret arguments and variables found in
+// text.
+func (i *Invocation) Redact(text string) string {
+	return string(i.secrets().redact([]byte(text)))
+}
+
+func (i *Invocation) secrets() *commandRequest {
+	return &commandRequest{args: i.Args, env: i.Env, secretArgs: i.secretArgs, secretEnv: i.secretEnv}
+}
+
+// ExecFunc performs an execution. Returning an ExitStatusError from the
+// function of NewFuncFactory reports an exit code, like a process failing on
+// its own.
+type ExecFunc func(inv *Invocation) error
+
+type ExitStatusError struct {
+	Code int
+}
+
+func (e *ExitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitStatusError) ExitCode() int {
+	return e.Code
+}
+
+type funcFactory struct {
+	fn ExecFunc
+}
+
+// NewFuncFactory returns a factory whose commands call fn instead of starting
+// a process, which is meant for fakes. Options and Run methods behave as with
+// real commands, errors returned by fn being reported as a CommandError.
+func NewFuncFactory(fn ExecFunc) CommandFactory {
+	return &funcFactory{fn: fn}
+}
+
+func (f *funcFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return &execCommand{commandRequest: commandRequest{ctx: ctx, cmd: cmd, args: slices.Clone(args), executor: f.invoke}}
+}
+
+func (f *funcFactory) invoke(req *commandRequest, s *streams) error {
+	ctx, cancel := req.context()
+	defer cancel()
+	inv := newInvocation(req, s)
+	inv.Context = ctx
+	if inv.Stdin == nil {
+		inv.Stdin = strings.NewReader("")
+	}
+	if inv.Stdout == nil {
+		inv.Stdout = io.Discard
+	}
+	stderrTail := &tailBuffer{limit: maxErrorStderrBytes}
+	if s.stderr == nil {
+		inv.Stderr = stderrTail
+	} else if !sameWriter(s.stdout, s.stderr) {
+		inv.Stderr = io.MultiWriter(s.stderr, stderrTail)
+	}
+
+	req.echo()
+	clock := req.clockOrDefault()
+	start := clock.Now()
+	err := f.fn(inv)
+	if err == nil {
+		return nil
+	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return err
+	}
+	if errors.Is(context.Cause(ctx), errCommandTimeout) {
+		err = &TimeoutError{Timeout: req.timeout, Err: err}
+	} else if req.ctx.Err() != nil && !errors.Is(err, req.ctx.Err()) {
+		err = &canceledError{cause: context.Cause(req.ctx), err: err}
+	}
+	return newCommandError(req, err, stderrTail.Bytes(), clock.Now().Sub(start))
+}
@@ -0,0 +1,146 @@
+package command
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// GzipCapture compresses the output written to it, in memory or to a file, so
+// the output of chatty commands can be kept as an artifact without holding it
+// whole. It is safe for concurrent use and can capture both streams at once.
+type GzipCapture struct {
+	mu      sync.Mutex
+	buffer  bytes.Buffer
+	file    *os.File
+	writer  *gzip.Writer
+	written int64
+	closed  bool
+	err     error
+}
+
+func NewGzipCapture() *GzipCapture {
+	c := &GzipCapture{}
+	c.writer = gzip.NewWriter(&c.buffer)
+	return c
+}
+
+// NewGzipFileCapture writes the compressed output to the file at path, which
+// is created or truncated.
+func NewGzipFileCapture(path string) (*GzipCapture, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+	return &GzipCapture{file: file, writer: gzip.NewWriter(file)}, nil
+}
+
+func (c *GzipCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.writer.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// Reset drops the output written so far, the capture starting over. It does
+// nothing once the capture is closed.
+func (c *GzipCapture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.written = 0
+	if c.file == nil {
+		c.buffer.Reset()
+		c.writer.Reset(&c.buffer)
+		return
+	}
+	if _, err := c.file.Seek(0, io.SeekStart); err != nil {
+		c.err = err
+		return
+	}
+	if err := c.file.Truncate(0); err != nil {
+		c.err = err
+		return
+	}
+	c.writer.Reset(c.file)
+}
+
+// Close ends the compressed stream, closing the file if any. Commands can no
+// longer write to the capture afterwards.
+func (c *GzipCapture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return c.err
+	}
+	c.closed = true
+	c.err = errors.Join(c.err, c.writer.Close())
+	if c.file != nil {
+		c.err = errors.Join(c.err, c.file.Close())
+	}
+	return c.err
+}
+
+// Len returns the number of uncompressed bytes written.
+func (c *GzipCapture) Len() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written
+}
+
+// Bytes returns the compressed output kept in memory, only complete once the
+// capture is closed.
+func (c *GzipCapture) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.buffer.Bytes()...)
+}
+
+// Reader returns the uncompressed output kept in memory.
+func (c *GzipCapture) Reader() (io.Reader, error) {
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	if c.file != nil {
+		return nil, fmt.Errorf("capture written to %s", c.file.Name())
+	}
+	return gzip.NewReader(bytes.NewReader(c.Bytes()))
+}
+
+// WithGzipCapture copies stdout and stderr to the given captures, either of
+// which can be nil. Passing the same capture twice stores the combined
+// output. The captures keep the output of every execution until closed with
+// Close, an execution repeated by WithRetry replacing the output of its
+// previous attempt.
+func WithGzipCapture(stdout *GzipCapture, stderr *GzipCapture) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			savedStdout, savedStderr := s.stdout, s.stderr
+			defer func() { s.stdout, s.stderr = savedStdout, savedStderr }()
+			var stdoutWriter, stderrWriter io.Writer
+			if stdout != nil {
+				stdoutWriter = stdout
+				s.addCapture(stdout)
+			}
+			if stderr != nil {
+				stderrWriter = stderr
+				s.addCapture(stderr)
+			}
+			s.tee(stdoutWriter, stderrWriter)
+			return next(req, s)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipCaptureKeepsRepeatedRuns(t *testing.T) {
+	capture := NewGzipCapture()
+	r := NewExecCmdFactory().Command(context.Background(), "echo", "out").With(WithGzipCapture(capture, nil))
+	for run := 0; run < 2; run++ {
+		if err := r.Run(); err != nil {
+			t.Fatalf("run %d: unexpected error: %v", run, err)
+		}
+	}
+	reader, err := capture.Reader()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output, _ := io.ReadAll(reader); string(output) != "out\nout\n" {
+		t.Errorf("expected the output of both runs, got %q", output)
+	}
+}
+
+func TestGzipFileCaptureKeepsLastRetriedAttempt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.gz")
+	capture, err := NewGzipFileCapture(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	marker := filepath.Join(t.TempDir(), "attempted")
+	flaky := NewExecCmdFactory().Command(context.Background(), "sh", "-c",
+		`if [ -e "$0" ]; then echo second; else touch "$0"; echo "first attempt"; exit 1; fi`, marker)
+	r := WithRetry(flaky.With(WithGzipCapture(capture, nil)), RetryPolicy{MaxAttempts: 2})
+	if err := r.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := capture.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output, _ := io.ReadAll(reader); string(output) != "second\n" {
+		t.Errorf("expected the output of the last attempt, got %q", output)
+	}
+}
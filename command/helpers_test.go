@@ -0,0 +1,62 @@
+package command_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// outputCommand is a command printing stdout, escaped so that it can hold any
+// byte.
+func outputCommand(stdout string) command.Runnable {
+	var format strings.Builder
+	for _, b := range []byte(stdout) {
+		fmt.Fprintf(&format, `\%03o`, b)
+	}
+	return command.NewExecCmdFactory().Command(context.Background(), "printf", format.String())
+}
+
+// runLog is a file where shell commands append their names as they run.
+type runLog string
+
+func newRunLog(t *testing.T) runLog {
+	return runLog(filepath.Join(t.TempDir(), "runs"))
+}
+
+// script makes sh record the run ahead of script, sh having the name of the
+// command as $0 and l as $1. RUN then holds the number of recorded runs and the
+// positional parameters the arguments of the command.
+func (l runLog) script(script string) string {
+	return `echo "$0" >> "$1"; RUN=$(($(wc -l < "$1"))); shift; ` + script
+}
+
+// command runs script with sh, name being $0 and args the positional
+// parameters.
+func (l runLog) command(ctx context.Context, name, script string, args ...string) command.Runnable {
+	return command.NewExecCmdFactory().Command(ctx, "sh", append([]string{"-c", l.script(script), name, string(l)}, args...)...)
+}
+
+// entries returns the names of the recorded runs.
+func (l runLog) entries() []string {
+	content, _ := os.ReadFile(string(l))
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func (l runLog) runs() int {
+	return len(l.entries())
+}
+
+// streamsCommand writes each of its writes to stdout, or to stderr when it
+// starts with "!", pausing after each one to keep their order.
+func streamsCommand(writes ...string) command.Runnable {
+	script := `for write; do case "$write" in "!"*) printf %s "${write#!}" >&2 ;; *) printf %s "$write" ;; esac; sleep 0.05; done`
+	return command.NewExecCmdFactory().Command(context.Background(), "sh", append([]string{"-c", script, "streams"}, writes...)...)
+}
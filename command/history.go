@@ -0,0 +1,89 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const historyStderrBytes = 4 << 10
+
+// Execution is a finished execution kept by a HistoryFactory.
+type Execution struct {
+	Start time.Time
+	// CommandLine has its secret arguments redacted, as Stderr.
+	CommandLine string
+	Dir         string
+	Duration    time.Duration
+	// ExitCode is -1 when the command did not exit on its own.
+	ExitCode int
+	Err      error
+	// Stderr holds the last few KiB written to stderr.
+	Stderr []byte
+}
+
+// HistoryFactory keeps the last executions of the commands of its inner
+// factory, for debug endpoints and error reports.
+type HistoryFactory struct {
+	inner CommandFactory
+
+	mu      sync.Mutex
+	entries []Execution
+	next    int
+	full    bool
+}
+
+// NewHistoryFactory keeps the last size executions, at least one.
+func NewHistoryFactory(inner CommandFactory, size int) *HistoryFactory {
+	return &HistoryFactory{inner: inner, entries: make([]Execution, max(size, 1))}
+}
+
+func (f *HistoryFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(f.record))
+}
+
+// History returns the kept executions, from the oldest to the latest.
+func (f *HistoryFactory) History() []Execution {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.full {
+		return append([]Execution(nil), f.entries[:f.next]...)
+	}
+	return append(append([]Execution(nil), f.entries[f.next:]...), f.entries[:f.next]...)
+}
+
+func (f *HistoryFactory) record(next execFunc) execFunc {
+	return func(req *commandRequest, s *streams) error {
+		stdout, stderr := s.stdout, s.stderr
+		defer func() { s.stdout, s.stderr = stdout, stderr }()
+		tail := &tailBuffer{limit: historyStderrBytes}
+		s.tee(nil, tail)
+
+		clock := req.clockOrDefault()
+		start := clock.Now()
+		err := next(req, s)
+		execution := Execution{
+			Start:       start,
+			CommandLine: Join(append([]string{req.cmd}, req.redactedArgs()...)...),
+			Dir:         req.dir,
+			Duration:    clock.Now().Sub(start),
+			Err:         err,
+			Stderr:      req.redact(append([]byte(nil), tail.Bytes()...)),
+		}
+		if err != nil {
+			execution.ExitCode = -1
+			var cmdErr *CommandError
+			if errors.As(err, &cmdErr) {
+				execution.ExitCode = cmdErr.ExitCode()
+			}
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.entries[f.next] = execution
+		f.next = (f.next + 1) % len(f.entries)
+		f.full = f.full || f.next == 0
+		return err
+	}
+}
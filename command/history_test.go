@@ -0,0 +1,42 @@
+package command_test
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestHistoryFactory(t *testing.T) {
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		if inv.Args[0] == "2" {
+			_, _ = io.WriteString(inv.Stderr, "failed with token t0ken")
+			return &command.ExitStatusError{Code: 9}
+		}
+		return nil
+	})
+	factory := command.NewHistoryFactory(inner, 2)
+	if history := factory.History(); len(history) != 0 {
+		t.Errorf("expected an empty history, got %v", history)
+	}
+	for index := range 3 {
+		_ = factory.Command(context.Background(), "step", strconv.Itoa(index), "t0ken").With(command.WithSecretArgs(1)).Run()
+	}
+	history := factory.History()
+	var lines []string
+	for _, execution := range history {
+		lines = append(lines, execution.CommandLine)
+	}
+	if !slices.Equal(lines, []string{"step 1 '***'", "step 2 '***'"}) {
+		t.Fatalf("expected the last executions from the oldest, got %q", lines)
+	}
+	if history[0].ExitCode != 0 || history[0].Err != nil {
+		t.Errorf("expected a successful execution, got %+v", history[0])
+	}
+	if history[1].ExitCode != 9 || history[1].Err == nil || string(history[1].Stderr) != "failed with token ***" {
+		t.Errorf("expected the failure with its redacted stderr, got %+v", history[1])
+	}
+}
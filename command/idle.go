@@ -0,0 +1,65 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var errIdleTimeout = errors.New("command idle timeout")
+
+// WithIdleTimeout stops the command when it writes nothing to stdout or stderr
+// for the given duration.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(r *commandRequest) {
+		r.idleTimeout = timeout
+	}
+}
+
+type idleMonitor struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	timer   Timer
+	cancel  context.CancelCauseFunc
+}
+
+func newIdleMonitor(ctx context.Context, timeout time.Duration, clock Clock) (context.Context, *idleMonitor) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	monitor := &idleMonitor{timeout: timeout, cancel: cancel}
+	monitor.timer = clock.AfterFunc(timeout, func() {
+		cancel(errIdleTimeout)
+	})
+	return ctx, monitor
+}
+
+func (m *idleMonitor) touch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timer.Reset(m.timeout)
+}
+
+func (m *idleMonitor) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timer.Stop()
+	m.cancel(nil)
+}
+
+func (m *idleMonitor) watch(w io.Writer) io.Writer {
+	if w == nil {
+		w = io.Discard
+	}
+	return &activityWriter{writer: w, monitor: m}
+}
+
+type activityWriter struct {
+	writer  io.Writer
+	monitor *idleMonitor
+}
+
+func (w *activityWriter) Write(p []byte) (int, error) {
+	w.monitor.touch()
+	return w.writer.Write(p)
+}
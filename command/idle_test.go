@@ -0,0 +1,32 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithIdleTimeout(t *testing.T) {
+	r := command.NewExecCmdFactory().
+		Command(context.Background(), "sh", "-c", "for i in 1 2 3 4 5; do echo $i; sleep 0.1; done; exec sleep 10").
+		With(command.WithIdleTimeout(300 * time.Millisecond))
+	start := time.Now()
+	output, err := r.RunStdout()
+	elapsed := time.Since(start)
+
+	var timeoutErr *command.TimeoutError
+	if !errors.As(err, &timeoutErr) || !timeoutErr.Idle {
+		t.Fatalf("expected an idle TimeoutError, got %v", err)
+	}
+	if string(output) != "1\n2\n3\n4\n5\n" {
+		t.Errorf("expected the output written before going idle, got %q", output)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("expected the idle command to be stopped, it ran for %s", elapsed)
+	}
+}
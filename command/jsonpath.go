@@ -0,0 +1,142 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type jsonPathStep struct {
+	key   string
+	index int
+	isKey bool
+}
+
+type jsonPathPostModifier struct {
+	path  string
+	steps []jsonPathStep
+	err   error
+}
+
+// NewJSONPathPostModifier parses the output as JSON and returns the value at
+// path, written as .field, [index] or ["key"] steps like .items[0].name and a
+// negative index counting from the end. Strings are returned unquoted and any
+// other value as JSON.
+func NewJSONPathPostModifier(path string) *jsonPathPostModifier {
+	steps, err := parseJSONPath(path)
+	return &jsonPathPostModifier{path: path, steps: steps, err: err}
+}
+
+func (m *jsonPathPostModifier) process(content string) (string, error) {
+	if m.err != nil {
+		return content, fmt.Errorf("invalid JSON path %q: %w", m.path, m.err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return content, fmt.Errorf("failed to parse output as JSON: %w", err)
+	}
+	for index, step := range m.steps {
+		var err error
+		if value, err = step.apply(value); err != nil {
+			return content, fmt.Errorf("JSON path %q at %s: %w", m.path, formatJSONPath(m.steps[:index+1]), err)
+		}
+	}
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return content, err
+	}
+	return string(encoded), nil
+}
+
+func (s jsonPathStep) apply(value any) (any, error) {
+	if s.isKey {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, errors.New("not an object")
+		}
+		field, ok := object[s.key]
+		if !ok {
+			return nil, errors.New("key not found")
+		}
+		return field, nil
+	}
+	array, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("not an array")
+	}
+	index := s.index
+	if index < 0 {
+		index += len(array)
+	}
+	if index < 0 || index >= len(array) {
+		return nil, fmt.Errorf("index out of range, length is %d", len(array))
+	}
+	return array[index], nil
+}
+
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	rest := path
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				if rest == "" && len(steps) == 0 {
+					return steps, nil
+				}
+				return nil, errors.New("empty key")
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end], isKey: true})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.New("missing ]")
+			}
+			inner := rest[1:end]
+			if strings.HasPrefix(inner, `"`) {
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted key %s", inner)
+				}
+				steps = append(steps, jsonPathStep{key: key, isKey: true})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				steps = append(steps, jsonPathStep{index: index})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest[0])
+		}
+	}
+	return steps, nil
+}
+
+func formatJSONPath(steps []jsonPathStep) string {
+	var path strings.Builder
+	for _, step := range steps {
+		if !step.isKey {
+			fmt.Fprintf(&path, "[%d]", step.index)
+		} else if strings.ContainsAny(step.key, `.[]"`) {
+			fmt.Fprintf(&path, "[%q]", step.key)
+		} else {
+			path.WriteString("." + step.key)
+		}
+	}
+	return path.String()
+}
@@ -0,0 +1,37 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestJSONPathPostModifier(t *testing.T) {
+	output := `{"items": [{"name": "a", "port": 80}, {"name": "b", "labels": {"app.kubernetes.io/name": "web"}}], "count": 12345678901234567890}`
+	runModifierTests(t, []modifierTest{
+		{name: "string", output: output, modifier: command.NewJSONPathPostModifier(".items[0].name"), expected: "a"},
+		{name: "number", output: output, modifier: command.NewJSONPathPostModifier(".items[0].port"), expected: "80"},
+		{name: "large number", output: output, modifier: command.NewJSONPathPostModifier(".count"), expected: "12345678901234567890"},
+		{name: "negative index", output: output, modifier: command.NewJSONPathPostModifier(".items[-1].name"), expected: "b"},
+		{name: "quoted key", output: output, modifier: command.NewJSONPathPostModifier(`.items[1].labels["app.kubernetes.io/name"]`), expected: "web"},
+		{name: "object", output: output, modifier: command.NewJSONPathPostModifier(".items[1].labels"), expected: `{"app.kubernetes.io/name":"web"}`},
+	})
+}
+
+func TestJSONPathPostModifierErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		output string
+		path   string
+	}{
+		"invalid path":  {output: `{}`, path: ".a["},
+		"invalid json":  {output: `{`, path: ".a"},
+		"missing key":   {output: `{"a": 1}`, path: ".b"},
+		"out of range":  {output: `[1]`, path: "[1]"},
+		"not an object": {output: `[1]`, path: ".a"},
+		"not an array":  {output: `{"a": 1}`, path: ".a[0]"},
+	} {
+		if _, err := outputCommand(test.output).RunStdoutStr(command.NewJSONPathPostModifier(test.path)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
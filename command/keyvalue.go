@@ -0,0 +1,56 @@
+package command
+
+import (
+	"errors"
+	"strings"
+)
+
+var errEmptySeparator = errors.New("empty key value separator")
+
+// RunKeyValues runs r and parses its output with ParseKeyValues. An empty sep
+// fails without running r.
+func RunKeyValues(r Runnable, sep string) (map[string]string, error) {
+	if sep == "" {
+		return nil, errEmptySeparator
+	}
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyValues(output, sep)
+}
+
+// ParseKeyValues parses lines like KEY<sep>value, as printed by env,
+// systemctl show or found in os-release. Values wrapped in single or double
+// quotes are unquoted, blank lines, comments and lines without sep are
+// skipped and the last value of a repeated key wins. sep must not be empty.
+func ParseKeyValues(output string, sep string) (map[string]string, error) {
+	if sep == "" {
+		return nil, errEmptySeparator
+	}
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, sep)
+		if !found {
+			continue
+		}
+		values[strings.TrimSpace(key)] = unquoteValue(strings.TrimSpace(value))
+	}
+	return values, nil
+}
+
+func unquoteValue(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' {
+		if unquoted, ok := unquoteEnvValue(value[1:], nil); ok {
+			return unquoted
+		}
+	}
+	return value
+}
@@ -0,0 +1,44 @@
+package command_test
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	output := "# os-release\nNAME=\"Fedora Linux\"\r\nID=fedora\n\nPRETTY='Fedora 40'\nnot a pair\nID = fedora2\nESCAPED=\"a \\\"b\\\"\"\n"
+	expected := map[string]string{
+		"NAME":    "Fedora Linux",
+		"ID":      "fedora2",
+		"PRETTY":  "Fedora 40",
+		"ESCAPED": `a "b"`,
+	}
+	if values, err := command.ParseKeyValues(output, "="); err != nil || !maps.Equal(values, expected) {
+		t.Errorf("expected %v, got %v and %v", expected, values, err)
+	}
+	if _, err := command.ParseKeyValues(output, ""); err == nil {
+		t.Error("expected an empty separator to be rejected")
+	}
+}
+
+func TestRunKeyValues(t *testing.T) {
+	values, err := command.RunKeyValues(outputCommand("ActiveState: active\nSubState: running\n"), ":")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["ActiveState"] != "active" || values["SubState"] != "running" {
+		t.Errorf("unexpected values %v", values)
+	}
+	failing, _ := flakyCommand(t, 1, 1)
+	var cmdErr *command.CommandError
+	if _, err := command.RunKeyValues(failing, "="); !errors.As(err, &cmdErr) {
+		t.Errorf("expected the command failure, got %v", err)
+	}
+	r, attempts := flakyCommand(t, 0, 0)
+	if _, err := command.RunKeyValues(r, ""); err == nil || attempts() != 0 {
+		t.Errorf("expected an empty separator to fail without running, got %v after %d runs", err, attempts())
+	}
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrOutputLimitExceeded = errors.New("output limit exceeded")
+
+type OutputLimitPolicy int
+
+const (
+	OutputLimitTruncate OutputLimitPolicy = iota
+	OutputLimitFail
+)
+
+// WithMaxOutputBytes bounds each buffer captured by the Run methods. Writers
+// supplied by the caller are not limited.
+func WithMaxOutputBytes(limit int, policy OutputLimitPolicy) Option {
+	return func(r *commandRequest) {
+		r.maxOutputBytes = limit
+		r.outputLimitPolicy = policy
+	}
+}
+
+type limitedCapture struct {
+	buffer   captureBuffer
+	limit    int
+	policy   OutputLimitPolicy
+	written  int
+	exceeded bool
+}
+
+func (l *limitedCapture) Write(p []byte) (int, error) {
+	remaining := l.limit - l.written
+	if len(p) <= remaining {
+		l.written += len(p)
+		return l.buffer.Write(p)
+	}
+	l.exceeded = true
+	l.written = l.limit
+	if _, err := l.buffer.Write(p[:remaining]); err != nil {
+		return 0, err
+	}
+	if l.policy == OutputLimitFail {
+		return remaining, ErrOutputLimitExceeded
+	}
+	return len(p), nil
+}
+
+func (l *limitedCapture) Reset() {
+	l.buffer.Reset()
+	l.written = 0
+	l.exceeded = false
+}
+
+func (s *streams) limitCaptures(limit int, policy OutputLimitPolicy) []*limitedCapture {
+	limited := make([]*limitedCapture, 0, len(s.captures))
+	for index, capture := range s.captures {
+		if index > 0 && sameWriter(capture, s.captures[index-1]) {
+			s.captures[index] = s.captures[index-1]
+			continue
+		}
+		wrapped := &limitedCapture{buffer: capture, limit: limit, policy: policy}
+		if sameWriter(s.stdout, capture) {
+			s.stdout = wrapped
+		}
+		if sameWriter(s.stderr, capture) {
+			s.stderr = wrapped
+		}
+		s.captures[index] = wrapped
+		limited = append(limited, wrapped)
+	}
+	return limited
+}
+
+func checkOutputLimits(limited []*limitedCapture, s *streams, err error) error {
+	for _, capture := range limited {
+		if !capture.exceeded {
+			continue
+		}
+		s.truncated = true
+		if capture.policy == OutputLimitFail && !errors.Is(err, ErrOutputLimitExceeded) {
+			limitErr := fmt.Errorf("%w: captured more than %d bytes", ErrOutputLimitExceeded, capture.limit)
+			if err == nil {
+				return limitErr
+			}
+			return errors.Join(limitErr, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package command_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithMaxOutputBytesTruncates(t *testing.T) {
+	r := outputCommand(strings.Repeat("x", 100)).With(command.WithMaxOutputBytes(10, command.OutputLimitTruncate))
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != strings.Repeat("x", 10) {
+		t.Errorf("expected the output truncated to 10 bytes, got %q", output)
+	}
+	result, err := r.Execute()
+	if err != nil || !result.Truncated || len(result.Stdout) != 10 {
+		t.Errorf("expected a truncated result, got %+v and %v", result, err)
+	}
+}
+
+func TestWithMaxOutputBytesFails(t *testing.T) {
+	r := outputCommand(strings.Repeat("x", 100)).With(command.WithMaxOutputBytes(10, command.OutputLimitFail))
+	output, err := r.RunStdout()
+	if !errors.Is(err, command.ErrOutputLimitExceeded) {
+		t.Fatalf("expected ErrOutputLimitExceeded, got %v", err)
+	}
+	if len(output) != 10 {
+		t.Errorf("expected the first 10 bytes, got %q", output)
+	}
+}
+
+func TestWithMaxOutputBytesLeavesCallerWritersAlone(t *testing.T) {
+	var stdout strings.Builder
+	r := outputCommand(strings.Repeat("x", 100)).With(command.WithMaxOutputBytes(10, command.OutputLimitFail))
+	if err := r.RunToWriter(&stdout, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.Len() != 100 {
+		t.Errorf("expected the whole output, got %d bytes", stdout.Len())
+	}
+}
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+type LoggingOptions struct {
+	// StartLevel is the level of the record logged before running, debug when
+	// nil.
+	StartLevel slog.Leveler
+	// EndLevel is the level of the record of successful executions, info when
+	// nil.
+	EndLevel slog.Leveler
+	// FailureLevel is the level of the record of failed executions, error
+	// when nil.
+	FailureLevel slog.Leveler
+	// OutputBytes adds up to this many bytes of stdout and stderr to the end
+	// record, none when zero. Secrets are redacted from them.
+	OutputBytes int
+}
+
+type loggingFactory struct {
+	inner  CommandFactory
+	logger *slog.Logger
+	opts   LoggingOptions
+}
+
+// NewLoggingFactory logs the start and end of every command of inner, with its
+// redacted arguments, duration and exit code.
+func NewLoggingFactory(inner CommandFactory, logger *slog.Logger, opts LoggingOptions) CommandFactory {
+	if opts.StartLevel == nil {
+		opts.StartLevel = slog.LevelDebug
+	}
+	if opts.EndLevel == nil {
+		opts.EndLevel = slog.LevelInfo
+	}
+	if opts.FailureLevel == nil {
+		opts.FailureLevel = slog.LevelError
+	}
+	return &loggingFactory{inner: inner, logger: logger, opts: opts}
+}
+
+func (f *loggingFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(f.log))
+}
+
+func (f *loggingFactory) log(next execFunc) execFunc {
+	return func(req *commandRequest, s *streams) error {
+		attrs := []slog.Attr{slog.String("cmd", req.cmd), slog.Any("args", req.redactedArgs())}
+		if req.dir != "" {
+			attrs = append(attrs, slog.String("dir", req.dir))
+		}
+		f.logger.LogAttrs(req.ctx, f.opts.StartLevel.Level(), "command started", attrs...)
+
+		var stdout, stderr *headBuffer
+		if f.opts.OutputBytes > 0 {
+			savedStdout, savedStderr := s.stdout, s.stderr
+			defer func() { s.stdout, s.stderr = savedStdout, savedStderr }()
+			stdout = newHeadBuffer(req, f.opts.OutputBytes)
+			stderr = newHeadBuffer(req, f.opts.OutputBytes)
+			s.tee(stdout, stderr)
+		}
+		clock := req.clockOrDefault()
+		start := clock.Now()
+		err := next(req, s)
+
+		attrs = append(attrs, slog.Duration("duration", clock.Now().Sub(start)))
+		level, message := f.opts.EndLevel.Level(), "command finished"
+		if err != nil {
+			level, message = f.opts.FailureLevel.Level(), "command failed"
+			var cmdErr *CommandError
+			if errors.As(err, &cmdErr) {
+				attrs = append(attrs, slog.Int("exit_code", cmdErr.ExitCode()))
+			}
+			attrs = append(attrs, slog.String("error", string(req.redact([]byte(err.Error())))))
+		} else {
+			attrs = append(attrs, slog.Int("exit_code", 0))
+		}
+		if stdout != nil {
+			attrs = append(attrs, slog.String("stdout", stdout.text(req, f.opts.OutputBytes)), slog.String("stderr", stderr.text(req, f.opts.OutputBytes)))
+		}
+		f.logger.LogAttrs(req.ctx, level, message, attrs...)
+		return err
+	}
+}
+
+// headBuffer keeps the first limit bytes written to it.
+type headBuffer struct {
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+// newHeadBuffer returns a buffer for the first size bytes of output, which
+// keeps secrets crossing the limit whole so they get redacted.
+func newHeadBuffer(req *commandRequest, size int) *headBuffer {
+	extra := 0
+	for _, secret := range req.secretValues() {
+		extra = max(extra, len(secret))
+	}
+	return &headBuffer{limit: size + extra}
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if remaining := h.limit - len(h.data); len(p) > remaining {
+		h.data = append(h.data, p[:remaining]...)
+		h.truncated = true
+	} else {
+		h.data = append(h.data, p...)
+	}
+	return len(p), nil
+}
+
+func (h *headBuffer) text(req *commandRequest, size int) string {
+	text := req.redact(h.data)
+	if len(text) > size {
+		return string(text[:size]) + "..."
+	}
+	if h.truncated {
+		return string(text) + "..."
+	}
+	return string(text)
+}
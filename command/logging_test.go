@@ -0,0 +1,68 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestNewLoggingFactory(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		if _, err := io.WriteString(inv.Stdout, "token is hunter2 and more output"); err != nil {
+			return err
+		}
+		_, _ = io.WriteString(inv.Stderr, "bad")
+		return &command.ExitStatusError{Code: 2}
+	})
+	factory := command.NewLoggingFactory(inner, logger, command.LoggingOptions{OutputBytes: 16})
+	err := factory.Command(context.Background(), "login", "--password", "hunter2").
+		With(command.WithSecretArgs(1), command.WithDir("/home")).Run()
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	records := logRecords(t, &buffer)
+	if len(records) != 2 {
+		t.Fatalf("expected a start and an end record, got %v", records)
+	}
+	start, end := records[0], records[1]
+	if start["level"] != "DEBUG" || start["msg"] != "command started" || start["cmd"] != "login" || start["dir"] != "/home" {
+		t.Errorf("unexpected start record %v", start)
+	}
+	if args, _ := start["args"].([]any); len(args) != 2 || args[1] != "***" {
+		t.Errorf("expected the secret argument to be redacted, got %v", start["args"])
+	}
+	expected := map[string]any{
+		"level":     "ERROR",
+		"msg":       "command failed",
+		"exit_code": 2.0,
+		"stdout":    "token is *** and...",
+		"stderr":    "bad",
+	}
+	for key, value := range expected {
+		if end[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, end)
+		}
+	}
+	if _, ok := end["duration"]; !ok {
+		t.Errorf("expected the duration, got %v", end)
+	}
+}
+
+func TestNewLoggingFactoryLevels(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+	factory := command.NewLoggingFactory(command.NewFuncFactory(func(*command.Invocation) error { return nil }), logger, command.LoggingOptions{EndLevel: slog.LevelWarn})
+	if err := factory.Command(context.Background(), "true").Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := logRecords(t, &buffer)
+	if len(records) != 1 || records[0]["level"] != "WARN" || records[0]["exit_code"] != 0.0 || records[0]["stdout"] != nil {
+		t.Errorf("expected only the end record at the given level without output, got %v", records)
+	}
+}
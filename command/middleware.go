@@ -0,0 +1,78 @@
+package command
+
+import (
+	"context"
+	"maps"
+)
+
+// Middleware wraps the executions of commands, for concerns like logging,
+// metrics or refreshing credentials. It can change the invocation before
+// calling next, including its context and streams, or skip next altogether.
+type Middleware func(next ExecFunc) ExecFunc
+
+// WithMiddleware wraps the execution of the command with mw. Later middlewares
+// wrap the ones added before them, so a middleware added before WithRetry sees
+// every attempt.
+func WithMiddleware(mw Middleware) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			stdin, stdout, stderr := s.stdin, s.stdout, s.stderr
+			defer func() { s.stdin, s.stdout, s.stderr = stdin, stdout, stderr }()
+			inv := newInvocation(req, s)
+			return mw(func(inv *Invocation) error {
+				derived := *req
+				derived.ctx = inv.Context
+				if derived.ctx == nil {
+					derived.ctx = context.Background()
+				}
+				derived.cmd = inv.Cmd
+				derived.args = inv.Args
+				derived.dir = inv.Dir
+				derived.fileEnv = nil
+				derived.env = inv.Env
+				s.stdin, s.stdout, s.stderr = inv.Stdin, inv.Stdout, inv.Stderr
+				return next(&derived, s)
+			})(inv)
+		}
+	})
+}
+
+// WithFactoryMiddleware wraps every command of the factory with mws, see
+// WithMiddleware.
+func WithFactoryMiddleware(mws ...Middleware) FactoryOption {
+	opts := make([]Option, 0, len(mws))
+	for _, mw := range mws {
+		opts = append(opts, WithMiddleware(mw))
+	}
+	return WithDefaultOptions(opts...)
+}
+
+type middlewareFactory struct {
+	inner CommandFactory
+	opts  []Option
+}
+
+// NewMiddlewareFactory wraps every command of inner with mws, see
+// WithMiddleware.
+func NewMiddlewareFactory(inner CommandFactory, mws ...Middleware) CommandFactory {
+	opts := make([]Option, 0, len(mws))
+	for _, mw := range mws {
+		opts = append(opts, WithMiddleware(mw))
+	}
+	return &middlewareFactory{inner: inner, opts: opts}
+}
+
+func (f *middlewareFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	return f.inner.Command(ctx, cmd, args...).With(f.opts...)
+}
+
+// mergedEnv returns the variables set on the command, the ones of WithEnv
+// taking precedence over the ones of env files.
+func (r *commandRequest) mergedEnv() map[string]string {
+	env := maps.Clone(r.fileEnv)
+	if env == nil {
+		env = make(map[string]string, len(r.env))
+	}
+	maps.Copy(env, r.env)
+	return env
+}
@@ -0,0 +1,78 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// recordMiddleware appends name to trace before and after the execution.
+func recordMiddleware(name string, trace *[]string) command.Middleware {
+	return func(next command.ExecFunc) command.ExecFunc {
+		return func(inv *command.Invocation) error {
+			*trace = append(*trace, name+" before")
+			err := next(inv)
+			*trace = append(*trace, name+" after")
+			return err
+		}
+	}
+}
+
+func TestNewMiddlewareFactory(t *testing.T) {
+	var trace []string
+	var seen *command.Invocation
+	inner := command.NewFuncFactory(func(inv *command.Invocation) error {
+		seen = inv
+		trace = append(trace, "run")
+		return nil
+	})
+	mutate := func(next command.ExecFunc) command.ExecFunc {
+		return func(inv *command.Invocation) error {
+			inv.Args = append(inv.Args, "--token", "abc")
+			inv.Env["TOKEN"] = "abc"
+			return next(inv)
+		}
+	}
+	factory := command.NewMiddlewareFactory(inner, recordMiddleware("first", &trace), recordMiddleware("second", &trace), mutate)
+	if err := factory.Command(context.Background(), "deploy").With(command.WithEnv(map[string]string{"A": "1"})).Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(trace, []string{"second before", "first before", "run", "first after", "second after"}) {
+		t.Errorf("expected later middlewares to wrap earlier ones, got %q", trace)
+	}
+	if !slices.Equal(seen.Args, []string{"--token", "abc"}) || seen.Env["TOKEN"] != "abc" || seen.Env["A"] != "1" {
+		t.Errorf("expected the changes of the middleware to reach the execution, got %v and %v", seen.Args, seen.Env)
+	}
+}
+
+func TestMiddlewareSkipsExecution(t *testing.T) {
+	denied := errors.New("denied")
+	ran := false
+	factory := command.NewMiddlewareFactory(command.NewFuncFactory(func(*command.Invocation) error {
+		ran = true
+		return nil
+	}), func(command.ExecFunc) command.ExecFunc {
+		return func(*command.Invocation) error { return denied }
+	})
+	if err := factory.Command(context.Background(), "rm").Run(); !errors.Is(err, denied) {
+		t.Errorf("expected the middleware error, got %v", err)
+	}
+	if ran {
+		t.Error("expected the command not to run")
+	}
+}
+
+func TestWithFactoryMiddleware(t *testing.T) {
+	var trace []string
+	factory := command.NewExecCmdFactory(command.WithFactoryMiddleware(recordMiddleware("mw", &trace)))
+	out, err := factory.Command(context.Background(), "echo", "hello").RunStdoutStr()
+	if err != nil || out != "hello\n" {
+		t.Fatalf("expected the command output, got %q and %v", out, err)
+	}
+	if !slices.Equal(trace, []string{"mw before", "mw after"}) {
+		t.Errorf("expected the middleware to wrap the execution, got %q", trace)
+	}
+}
@@ -0,0 +1,283 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrNoMatch = errors.New("no match in output")
+
+type regexExtractPostModifier struct {
+	pattern *regexp.Regexp
+	err     error
+	group   int
+	all     bool
+}
+
+// NewRegexExtractPostModifier returns the given capture group, zero being the
+// whole match, of the first match of pattern in the output.
+func NewRegexExtractPostModifier(pattern string, group int) *regexExtractPostModifier {
+	compiled, err := regexp.Compile(pattern)
+	return &regexExtractPostModifier{pattern: compiled, err: err, group: group}
+}
+
+// NewRegexExtractAllPostModifier is like NewRegexExtractPostModifier but
+// returns the group of every match, one per line.
+func NewRegexExtractAllPostModifier(pattern string, group int) *regexExtractPostModifier {
+	modifier := NewRegexExtractPostModifier(pattern, group)
+	modifier.all = true
+	return modifier
+}
+
+func (m *regexExtractPostModifier) process(content string) (string, error) {
+	if m.err != nil {
+		return content, fmt.Errorf("invalid extract pattern: %w", m.err)
+	}
+	if m.group < 0 || m.group > m.pattern.NumSubexp() {
+		return content, fmt.Errorf("pattern %q has no group %d", m.pattern, m.group)
+	}
+	limit := 1
+	if m.all {
+		limit = -1
+	}
+	matches := m.pattern.FindAllStringSubmatch(content, limit)
+	if len(matches) == 0 {
+		return content, fmt.Errorf("%w: pattern %q", ErrNoMatch, m.pattern)
+	}
+	groups := make([]string, len(matches))
+	for index, match := range matches {
+		groups[index] = match[m.group]
+	}
+	return strings.Join(groups, "\n"), nil
+}
+
+type regexReplacePostModifier struct {
+	pattern     *regexp.Regexp
+	err         error
+	replacement string
+}
+
+// NewRegexReplacePostModifier replaces every match of pattern, replacement
+// can reference groups as in regexp.Regexp.Expand.
+func NewRegexReplacePostModifier(pattern string, replacement string) *regexReplacePostModifier {
+	compiled, err := regexp.Compile(pattern)
+	return &regexReplacePostModifier{pattern: compiled, err: err, replacement: replacement}
+}
+
+func (m *regexReplacePostModifier) process(content string) (string, error) {
+	if m.err != nil {
+		return content, fmt.Errorf("invalid replace pattern: %w", m.err)
+	}
+	return m.pattern.ReplaceAllString(content, m.replacement), nil
+}
+
+// ansiSequence matches CSI sequences such as colors and cursor movement, OSC
+// sequences such as titles and hyperlinks, and the remaining escapes like
+// charset or keypad selection.
+var ansiSequence = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[ -/]*[0-~])`)
+
+type stripANSIPostModifier struct{}
+
+func NewStripANSIPostModifier() *stripANSIPostModifier {
+	return &stripANSIPostModifier{}
+}
+
+func (m *stripANSIPostModifier) process(content string) (string, error) {
+	return stripANSI(content), nil
+}
+
+func stripANSI(content string) string {
+	if !strings.Contains(content, "\x1b") {
+		return content
+	}
+	return ansiSequence.ReplaceAllString(content, "")
+}
+
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+	trailing := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), trailing
+}
+
+func joinLines(lines []string, trailing bool) string {
+	joined := strings.Join(lines, "\n")
+	if trailing && len(lines) > 0 {
+		joined += "\n"
+	}
+	return joined
+}
+
+type lineFilterPostModifier struct {
+	match   func(line string) bool
+	exclude bool
+	err     error
+}
+
+var errNilLineMatch = errors.New("nil line filter match")
+
+// NewLineFilterPostModifier keeps the output lines for which match returns
+// true. A nil match makes the modifier fail.
+func NewLineFilterPostModifier(match func(line string) bool) *lineFilterPostModifier {
+	if match == nil {
+		return &lineFilterPostModifier{err: errNilLineMatch}
+	}
+	return &lineFilterPostModifier{match: match}
+}
+
+// NewLineExcludePostModifier drops the output lines for which match returns
+// true. A nil match makes the modifier fail.
+func NewLineExcludePostModifier(match func(line string) bool) *lineFilterPostModifier {
+	if match == nil {
+		return &lineFilterPostModifier{err: errNilLineMatch}
+	}
+	return &lineFilterPostModifier{match: match, exclude: true}
+}
+
+func NewLineRegexFilterPostModifier(pattern string) *lineFilterPostModifier {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return &lineFilterPostModifier{err: fmt.Errorf("invalid line filter pattern: %w", err)}
+	}
+	return NewLineFilterPostModifier(compiled.MatchString)
+}
+
+func NewLinePrefixFilterPostModifier(prefix string) *lineFilterPostModifier {
+	return NewLineFilterPostModifier(func(line string) bool {
+		return strings.HasPrefix(line, prefix)
+	})
+}
+
+func NewLineContainsFilterPostModifier(substr string) *lineFilterPostModifier {
+	return NewLineFilterPostModifier(func(line string) bool {
+		return strings.Contains(line, substr)
+	})
+}
+
+func (m *lineFilterPostModifier) process(content string) (string, error) {
+	if m.err != nil {
+		return content, m.err
+	}
+	lines, trailing := splitLines(content)
+	kept := lines[:0:0]
+	for _, line := range lines {
+		if m.match(line) != m.exclude {
+			kept = append(kept, line)
+		}
+	}
+	return joinLines(kept, trailing), nil
+}
+
+type headPostModifier struct {
+	lines int
+	tail  bool
+}
+
+// NewHeadPostModifier keeps the first n lines of the output.
+func NewHeadPostModifier(n int) *headPostModifier {
+	return &headPostModifier{lines: n}
+}
+
+// NewTailPostModifier keeps the last n lines of the output.
+func NewTailPostModifier(n int) *headPostModifier {
+	return &headPostModifier{lines: n, tail: true}
+}
+
+func (m *headPostModifier) process(content string) (string, error) {
+	if m.lines < 0 {
+		return content, fmt.Errorf("invalid line count: %d", m.lines)
+	}
+	lines, trailing := splitLines(content)
+	if len(lines) <= m.lines {
+		return content, nil
+	}
+	if m.tail {
+		return joinLines(lines[len(lines)-m.lines:], trailing), nil
+	}
+	return joinLines(lines[:m.lines], trailing), nil
+}
+
+type stringPostModifier struct {
+	transform func(content string) string
+}
+
+func (m *stringPostModifier) process(content string) (string, error) {
+	return m.transform(content), nil
+}
+
+func NewToLowerPostModifier() *stringPostModifier {
+	return &stringPostModifier{transform: strings.ToLower}
+}
+
+func NewToUpperPostModifier() *stringPostModifier {
+	return &stringPostModifier{transform: strings.ToUpper}
+}
+
+// NewCollapseWhitespacePostModifier replaces every run of whitespace, new
+// lines included, with a single space and trims both ends.
+func NewCollapseWhitespacePostModifier() *stringPostModifier {
+	return &stringPostModifier{transform: func(content string) string {
+		return strings.Join(strings.Fields(content), " ")
+	}}
+}
+
+var ErrUnexpectedOutput = errors.New("unexpected output")
+
+var errNilValidate = errors.New("nil validate function")
+
+type validatePostModifier struct {
+	validate func(content string) error
+	err      error
+}
+
+// NewValidatePostModifier fails when validate returns an error, otherwise the
+// output is left untouched. It is meant for tools reporting failures only in
+// their output. A nil validate makes the modifier fail.
+func NewValidatePostModifier(validate func(content string) error) *validatePostModifier {
+	if validate == nil {
+		return &validatePostModifier{err: errNilValidate}
+	}
+	return &validatePostModifier{validate: validate}
+}
+
+// NewValidateRegexPostModifier fails with ErrUnexpectedOutput unless the
+// output matches pattern.
+func NewValidateRegexPostModifier(pattern string) *validatePostModifier {
+	return newRegexValidator(pattern, true)
+}
+
+// NewRejectRegexPostModifier fails with ErrUnexpectedOutput when the output
+// matches pattern.
+func NewRejectRegexPostModifier(pattern string) *validatePostModifier {
+	return newRegexValidator(pattern, false)
+}
+
+func newRegexValidator(pattern string, mustMatch bool) *validatePostModifier {
+	compiled, err := regexp.Compile(pattern)
+	return NewValidatePostModifier(func(content string) error {
+		if err != nil {
+			return fmt.Errorf("invalid validation pattern: %w", err)
+		}
+		location := compiled.FindStringIndex(content)
+		if mustMatch && location == nil {
+			return fmt.Errorf("%w: pattern %q not found", ErrUnexpectedOutput, compiled)
+		}
+		if !mustMatch && location != nil {
+			return fmt.Errorf("%w: found %q", ErrUnexpectedOutput, content[location[0]:location[1]])
+		}
+		return nil
+	})
+}
+
+func (m *validatePostModifier) process(content string) (string, error) {
+	if m.err != nil {
+		return content, m.err
+	}
+	if err := m.validate(content); err != nil {
+		return content, err
+	}
+	return content, nil
+}
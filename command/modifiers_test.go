@@ -0,0 +1,232 @@
+package command_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+type modifierTest struct {
+	name     string
+	output   string
+	modifier command.RunnablePostModifier
+	expected string
+}
+
+func runModifierTests(t *testing.T, tests []modifierTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := outputCommand(test.output).RunStdoutStr(test.modifier)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestRegexExtractPostModifier(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "group", output: "version 1.2.3 and 4.5.6", modifier: command.NewRegexExtractPostModifier(`(\d+)\.(\d+)\.\d+`, 2), expected: "2"},
+		{name: "whole match", output: "version 1.2.3", modifier: command.NewRegexExtractPostModifier(`\d+\.\d+\.\d+`, 0), expected: "1.2.3"},
+		{name: "all", output: "a=1\nb=2\n", modifier: command.NewRegexExtractAllPostModifier(`(\w)=\d`, 1), expected: "a\nb"},
+	})
+}
+
+func TestRegexExtractPostModifierErrors(t *testing.T) {
+	if _, err := outputCommand("abc").RunStdoutStr(command.NewRegexExtractPostModifier(`\d+`, 0)); !errors.Is(err, command.ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+	for _, modifier := range []command.RunnablePostModifier{
+		command.NewRegexExtractPostModifier(`(`, 0),
+		command.NewRegexExtractPostModifier(`(a)`, 2),
+	} {
+		if _, err := outputCommand("abc").RunStdoutStr(modifier); err == nil || errors.Is(err, command.ErrNoMatch) {
+			t.Errorf("expected an invalid pattern error, got %v", err)
+		}
+	}
+}
+
+func TestRegexReplacePostModifier(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "literal", output: "a-b-c", modifier: command.NewRegexReplacePostModifier(`-`, "+"), expected: "a+b+c"},
+		{name: "groups", output: "john smith", modifier: command.NewRegexReplacePostModifier(`(\w+) (\w+)`, "$2, $1"), expected: "smith, john"},
+	})
+	if _, err := outputCommand("abc").RunStdoutStr(command.NewRegexReplacePostModifier(`[`, "")); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
+
+func TestStripANSIPostModifier(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "colors", output: "\x1b[1;31merror\x1b[0m: failed", modifier: command.NewStripANSIPostModifier(), expected: "error: failed"},
+		{name: "cursor", output: "\x1b[2K\x1b[1Gdone", modifier: command.NewStripANSIPostModifier(), expected: "done"},
+		{name: "hyperlink", output: "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x07", modifier: command.NewStripANSIPostModifier(), expected: "link"},
+		{name: "charset", output: "\x1b(Bplain", modifier: command.NewStripANSIPostModifier(), expected: "plain"},
+	})
+}
+
+func TestLineFilterPostModifiers(t *testing.T) {
+	output := "ok 1\nskip 2\nok 3\n"
+	runModifierTests(t, []modifierTest{
+		{name: "filter", output: output, modifier: command.NewLineFilterPostModifier(func(line string) bool { return line != "skip 2" }), expected: "ok 1\nok 3\n"},
+		{name: "exclude", output: output, modifier: command.NewLineExcludePostModifier(func(line string) bool { return line == "skip 2" }), expected: "ok 1\nok 3\n"},
+		{name: "regex", output: output, modifier: command.NewLineRegexFilterPostModifier(`\d$`), expected: output},
+		{name: "prefix", output: output, modifier: command.NewLinePrefixFilterPostModifier("skip"), expected: "skip 2\n"},
+		{name: "contains", output: "a\nb", modifier: command.NewLineContainsFilterPostModifier("b"), expected: "b"},
+		{name: "none kept", output: output, modifier: command.NewLinePrefixFilterPostModifier("none"), expected: ""},
+	})
+	if _, err := outputCommand(output).RunStdoutStr(command.NewLineRegexFilterPostModifier(`(`)); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+	for _, modifier := range []command.RunnablePostModifier{command.NewLineFilterPostModifier(nil), command.NewLineExcludePostModifier(nil)} {
+		if _, err := outputCommand(output).RunStdoutStr(modifier); err == nil {
+			t.Error("expected a nil match to fail")
+		}
+	}
+}
+
+func TestHeadAndTailPostModifiers(t *testing.T) {
+	output := "1\n2\n3\n"
+	runModifierTests(t, []modifierTest{
+		{name: "head", output: output, modifier: command.NewHeadPostModifier(2), expected: "1\n2\n"},
+		{name: "tail", output: output, modifier: command.NewTailPostModifier(2), expected: "2\n3\n"},
+		{name: "without trailing newline", output: "1\n2\n3", modifier: command.NewTailPostModifier(1), expected: "3"},
+		{name: "more than available", output: output, modifier: command.NewHeadPostModifier(5), expected: output},
+		{name: "zero", output: output, modifier: command.NewHeadPostModifier(0), expected: ""},
+	})
+	if _, err := outputCommand(output).RunStdoutStr(command.NewHeadPostModifier(-1)); err == nil {
+		t.Error("expected a negative count to fail")
+	}
+}
+
+func TestNormalizationPostModifiers(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "lower", output: "MiXeD", modifier: command.NewToLowerPostModifier(), expected: "mixed"},
+		{name: "upper", output: "MiXeD", modifier: command.NewToUpperPostModifier(), expected: "MIXED"},
+		{name: "collapse", output: "  a\t b\n\nc  ", modifier: command.NewCollapseWhitespacePostModifier(), expected: "a b c"},
+	})
+}
+
+func TestPostModifierFunc(t *testing.T) {
+	reverse := command.PostModifierFunc(func(content string) (string, error) {
+		runes := []rune(content)
+		slices.Reverse(runes)
+		return string(runes), nil
+	})
+	runModifierTests(t, []modifierTest{
+		{name: "func", output: "abc", modifier: reverse, expected: "cba"},
+	})
+	failure := errors.New("rejected")
+	reject := command.PostModifierFunc(func(content string) (string, error) {
+		return content, failure
+	})
+	if _, err := outputCommand("abc").RunStdoutStr(reject); !errors.Is(err, failure) {
+		t.Errorf("expected the error of the function, got %v", err)
+	}
+}
+
+func TestRunCombinedStrModifiers(t *testing.T) {
+	r := streamsCommand("OUT\n", "!ERR\n")
+	out, err := r.RunCombinedStr(command.NewToLowerPostModifier(), command.NewLinePrefixFilterPostModifier("err"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "err\n" {
+		t.Errorf("expected the modifiers to apply to the combined output, got %q", out)
+	}
+}
+
+func TestModifierErrorPolicy(t *testing.T) {
+	failure := errors.New("rejected")
+	reject := command.PostModifierFunc(func(content string) (string, error) {
+		return "ignored", failure
+	})
+	modifiers := []command.RunnablePostModifier{command.NewToUpperPostModifier(), reject, command.NewRegexReplacePostModifier("B", "c")}
+	tests := []struct {
+		policy   command.ModifierErrorPolicy
+		expected string
+	}{
+		{policy: command.ModifierFailFast, expected: ""},
+		{policy: command.ModifierReturnPartial, expected: "AB"},
+		{policy: command.ModifierSkipAndContinue, expected: "Ac"},
+	}
+	for _, test := range tests {
+		out, err := outputCommand("ab").With(command.WithModifierErrorPolicy(test.policy)).RunStdoutStr(modifiers...)
+		var modifierErr *command.ModifierError
+		if !errors.As(err, &modifierErr) || modifierErr.Index != 1 || !errors.Is(err, failure) {
+			t.Errorf("%d: expected the failure of modifier 1, got %v", test.policy, err)
+		}
+		if out != test.expected {
+			t.Errorf("%d: expected %q, got %q", test.policy, test.expected, out)
+		}
+	}
+}
+
+func TestModifierErrorPolicyInRunLines(t *testing.T) {
+	failure := errors.New("rejected")
+	rejectB := command.PostModifierFunc(func(content string) (string, error) {
+		if content == "b" {
+			return content, failure
+		}
+		return strings.ToUpper(content), nil
+	})
+	tests := []struct {
+		policy   command.ModifierErrorPolicy
+		expected []string
+	}{
+		{policy: command.ModifierFailFast, expected: nil},
+		{policy: command.ModifierReturnPartial, expected: []string{"A"}},
+		{policy: command.ModifierSkipAndContinue, expected: []string{"A", "b", "C"}},
+	}
+	for _, test := range tests {
+		lines, err := outputCommand("a\nb\nc\n").With(command.WithModifierErrorPolicy(test.policy)).RunLines(rejectB)
+		if !errors.Is(err, failure) || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%d: expected the failure of line 2, got %v", test.policy, err)
+		}
+		if !slices.Equal(lines, test.expected) {
+			t.Errorf("%d: expected %q, got %q", test.policy, test.expected, lines)
+		}
+	}
+}
+
+func TestTrimPostModifiers(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "space", output: " \t value \r\n", modifier: command.NewTrimSpacePostModifier(), expected: "value"},
+		{name: "newline", output: " value\r\n\n", modifier: command.NewTrimNewlinePostModifier(), expected: " value"},
+		{name: "cutset left", output: "xyxvalue", modifier: command.NewTrimCutsetPostModifier(command.PostModifierTrimLeft, "xy"), expected: "value"},
+		{name: "cutset both", output: "--value-", modifier: command.NewTrimCutsetPostModifier(command.PostModifierTrimBoth, "-"), expected: "value"},
+	})
+}
+
+func TestValidatePostModifiers(t *testing.T) {
+	runModifierTests(t, []modifierTest{
+		{name: "validate", output: "ok", modifier: command.NewValidatePostModifier(func(string) error { return nil }), expected: "ok"},
+		{name: "must match", output: "status: ok", modifier: command.NewValidateRegexPostModifier(`status: \w+`), expected: "status: ok"},
+		{name: "reject", output: "status: ok", modifier: command.NewRejectRegexPostModifier(`(?i)error`), expected: "status: ok"},
+	})
+	for name, modifier := range map[string]command.RunnablePostModifier{
+		"must match": command.NewValidateRegexPostModifier(`status: ok`),
+		"reject":     command.NewRejectRegexPostModifier(`(?i)error`),
+	} {
+		if _, err := outputCommand("ERROR: disk full").RunStdoutStr(modifier); !errors.Is(err, command.ErrUnexpectedOutput) {
+			t.Errorf("%s: expected ErrUnexpectedOutput, got %v", name, err)
+		}
+	}
+	failure := errors.New("rejected")
+	if _, err := outputCommand("x").RunStdoutStr(command.NewValidatePostModifier(func(string) error { return failure })); !errors.Is(err, failure) {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+	if _, err := outputCommand("x").RunStdoutStr(command.NewValidateRegexPostModifier(`(`)); err == nil || errors.Is(err, command.ErrUnexpectedOutput) {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+	if _, err := outputCommand("x").RunStdoutStr(command.NewValidatePostModifier(nil)); err == nil {
+		t.Error("expected a nil validate to fail")
+	}
+}
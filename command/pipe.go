@@ -0,0 +1,183 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedPipeStage is returned for pipelines holding a Runnable that
+// was not created by a factory of this package, Pipe needs to start their
+// processes itself to wire them together.
+var ErrUnsupportedPipeStage = errors.New("unsupported pipeline stage")
+
+var (
+	errPipeStageFailed = errors.New("another pipeline stage failed")
+	errPipeCanceled    = errors.New("pipeline canceled")
+)
+
+type PipeStageError struct {
+	Stage int
+	Err   error
+}
+
+func (e *PipeStageError) Error() string {
+	return fmt.Sprintf("pipeline stage %d: %v", e.Stage, e.Err)
+}
+
+func (e *PipeStageError) Unwrap() error {
+	return e.Err
+}
+
+type pipeline struct {
+	stages []*execCommand
+}
+
+// Pipe connects the stdout of each runnable to the stdin of the next one
+// without a shell. The stderr of every stage goes to the pipeline stderr and
+// failures are reported as the errors.Join of PipeStageError values, one per
+// failed stage, each wrapping the CommandError of its stage. A failing stage
+// cancels the rest, except when it failed to write to a stage that had
+// already exited. Options given later to With apply to the pipeline as a
+// whole. Runnables not created by the factories of this package cannot be
+// stages, the returned Runnable fails with ErrUnsupportedPipeStage.
+func Pipe(runnables ...Runnable) Runnable {
+	if len(runnables) == 0 {
+		return &execCommand{commandRequest: commandRequest{ctx: context.Background(), err: errors.New("empty pipeline")}}
+	}
+	p := &pipeline{}
+	lines := make([]string, len(runnables))
+	for index, runnable := range runnables {
+		stage, ok := runnable.(*execCommand)
+		if !ok {
+			return &execCommand{commandRequest: commandRequest{ctx: context.Background(), err: fmt.Errorf("%w: stage %d is a %T", ErrUnsupportedPipeStage, index, runnable)}}
+		}
+		p.stages = append(p.stages, stage)
+		lines[index] = Join(append([]string{stage.cmd}, stage.redactedArgs()...)...)
+	}
+	return &execCommand{commandRequest: commandRequest{
+		ctx:      p.stages[0].ctx,
+		cmd:      strings.Join(lines, " | "),
+		executor: p.run,
+	}}
+}
+
+func (p *pipeline) run(req *commandRequest, s *streams) error {
+	ctx, cancel := req.context()
+	defer cancel()
+
+	stdout, stderr := s.stdout, s.stderr
+	if _, ok := stderr.(*os.File); stderr != nil && !ok {
+		shared := &lockedWriter{writer: stderr}
+		if sameWriter(stdout, stderr) {
+			stdout = shared
+		}
+		stderr = shared
+	}
+
+	stageStreams := make([]*streams, len(p.stages))
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for index := range p.stages {
+		stageStreams[index] = &streams{stdin: s.stdin, stdout: stdout, stderr: stderr}
+		if index > 0 {
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				return err
+			}
+			files = append(files, reader, writer)
+			stageStreams[index-1].stdout = writer
+			stageStreams[index].stdin = reader
+		}
+	}
+	if s.onStart != nil {
+		p.notifyStart(s, stageStreams)
+	}
+
+	stageCtx, cancelStages := context.WithCancelCause(ctx)
+	defer cancelStages(nil)
+	errs := make([]error, len(p.stages))
+	var wg sync.WaitGroup
+	for index, stage := range p.stages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Closing the pipe ends of a finished stage delivers EOF downstream
+			// and EPIPE upstream.
+			defer func() {
+				if writer, ok := stageStreams[index].stdout.(*os.File); ok && index < len(p.stages)-1 {
+					writer.Close()
+				}
+				if reader, ok := stageStreams[index].stdin.(*os.File); ok && index > 0 {
+					reader.Close()
+				}
+			}()
+			runCtx, cancelRun := context.WithCancelCause(stage.ctx)
+			defer cancelRun(nil)
+			// The pipeline timeout is reported once for the whole pipeline.
+			stop := context.AfterFunc(stageCtx, func() {
+				cancelRun(errPipeCanceled)
+			})
+			defer stop()
+			run := &execCommand{commandRequest: stage.clone()}
+			run.ctx = runCtx
+			err := run.run(stageStreams[index])
+			if err == nil || (index < len(p.stages)-1 && isBrokenPipe(err)) {
+				return
+			}
+			if errors.Is(context.Cause(stageCtx), errPipeStageFailed) {
+				return
+			}
+			errs[index] = &PipeStageError{Stage: index, Err: err}
+			cancelStages(errPipeStageFailed)
+		}()
+	}
+	wg.Wait()
+	if s.onExit != nil {
+		s.onExit()
+	}
+
+	err := errors.Join(errs...)
+	if err != nil && errors.Is(context.Cause(ctx), errCommandTimeout) {
+		err = &TimeoutError{Timeout: req.timeout, Err: err}
+	}
+	return err
+}
+
+// notifyStart reports the last stage as the started process once every stage
+// is running, signals are delivered to all of them.
+func (p *pipeline) notifyStart(s *streams, stageStreams []*streams) {
+	var mu sync.Mutex
+	signals := make([]func(os.Signal) error, len(stageStreams))
+	var last *os.Process
+	pending := len(stageStreams)
+	signalAll := func(sig os.Signal) error {
+		var errs []error
+		for _, signal := range signals {
+			if err := signal(sig); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for index, stage := range stageStreams {
+		stage.onStart = func(process *os.Process, signal func(os.Signal) error) {
+			mu.Lock()
+			defer mu.Unlock()
+			signals[index] = signal
+			if index == len(stageStreams)-1 {
+				last = process
+			}
+			if pending--; pending == 0 {
+				s.onStart(last, signalAll)
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package command
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, io.ErrClosedPipe) || errors.Is(err, os.ErrClosed)
+}
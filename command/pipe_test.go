@@ -0,0 +1,105 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func pipeStage(cmd string, args ...string) command.Runnable {
+	return command.NewExecCmdFactory().Command(context.Background(), cmd, args...)
+}
+
+func TestPipe(t *testing.T) {
+	out, err := command.Pipe(pipeStage("printf", `b\na\n`), pipeStage("sort"), pipeStage("tr", "a-z", "A-Z")).RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "A\nB\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestPipeFeedsInputToTheFirstStage(t *testing.T) {
+	out, err := command.Pipe(pipeStage("cat"), pipeStage("tr", "a-z", "A-Z")).RunWithInputStr("abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "ABC" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestPipeReportsFailedStages(t *testing.T) {
+	err := command.Pipe(pipeStage("sh", "-c", "exit 3"), pipeStage("cat")).Run()
+	var stageErr *command.PipeStageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != 0 {
+		t.Fatalf("expected the first stage to fail, got %v", err)
+	}
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 3 {
+		t.Errorf("expected exit code 3, got %v", err)
+	}
+	if !errors.As(err, &cmdErr) || cmdErr.Command() != "sh" {
+		t.Errorf("expected the CommandError of the failed stage, got %v", err)
+	}
+}
+
+func TestPipeIgnoresWritesToExitedStages(t *testing.T) {
+	out, err := command.Pipe(pipeStage("yes"), pipeStage("head", "-n", "1")).RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "y\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestPipeFailureCancelsOtherStages(t *testing.T) {
+	start := time.Now()
+	err := command.Pipe(pipeStage("sleep", "30"), pipeStage("sh", "-c", "exit 1")).Run()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the pipeline to stop with its failed stage, took %s", elapsed)
+	}
+	var stageErr *command.PipeStageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != 1 || strings.Contains(err.Error(), "stage 0") {
+		t.Errorf("expected only the failed stage to be reported, got %v", err)
+	}
+}
+
+func TestPipeWithInProcessStages(t *testing.T) {
+	out, err := command.Pipe(outputCommand("x\n"), pipeStage("tr", "x", "y")).RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "y\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestEmptyPipe(t *testing.T) {
+	if err := command.Pipe().Run(); err == nil || !strings.Contains(err.Error(), "empty pipeline") {
+		t.Errorf("expected an empty pipeline error, got %v", err)
+	}
+}
+
+// foreignRunnable is a Runnable not created by the factories of the package.
+type foreignRunnable struct {
+	command.Runnable
+}
+
+func TestPipeRejectsUnsupportedStages(t *testing.T) {
+	r := command.Pipe(pipeStage("echo"), foreignRunnable{pipeStage("cat")})
+	if !errors.Is(r.Err(), command.ErrUnsupportedPipeStage) {
+		t.Errorf("expected ErrUnsupportedPipeStage before running, got %v", r.Err())
+	}
+	if err := r.Run(); !errors.Is(err, command.ErrUnsupportedPipeStage) {
+		t.Errorf("expected ErrUnsupportedPipeStage, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package command
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+func isBrokenPipe(err error) bool {
+	if errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGPIPE
+}
@@ -0,0 +1,10 @@
+package command
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var ErrPolicyViolation = errors.New("command not allowed by policy")
+
+type Policy struct {
+	// AllowedCommands restricts the executables that can run. Entries without
+	// a path separator match that command name, looked up in PATH, and the
+	// path PATH resolves it to. The other entries match that exact path.
+	// Empty allows all.
+	AllowedCommands []string
+	// ForbiddenArgs rejects commands having any argument matching one of the
+	// patterns.
+	ForbiddenArgs []*regexp.Regexp
+	// RequireAbsolutePath rejects commands not given as absolute paths.
+	RequireAbsolutePath bool
+	// MaxConcurrency bounds the commands running at once, zero is unbounded.
+	MaxConcurrency int
+}
+
+type policyFactory struct {
+	inner  CommandFactory
+	policy Policy
+	slots  chan struct{}
+}
+
+// NewPolicyFactory returns a factory rejecting the commands not allowed by
+// policy with ErrPolicyViolation. The executables and arguments run by inner
+// are checked when created and again before each execution.
+func NewPolicyFactory(inner CommandFactory, policy Policy) CommandFactory {
+	f := &policyFactory{inner: inner, policy: policy}
+	if policy.MaxConcurrency > 0 {
+		f.slots = make(chan struct{}, policy.MaxConcurrency)
+	}
+	return f
+}
+
+func (f *policyFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	runnable := f.inner.Command(ctx, cmd, args...)
+	// The executed command can differ from cmd, like with shell factories.
+	if created, ok := runnable.(*execCommand); ok {
+		if err := f.policy.check(created.cmd, created.args); err != nil {
+			return runnable.With(withError(err))
+		}
+	}
+	return runnable.With(withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			if err := f.policy.check(req.cmd, req.args); err != nil {
+				return err
+			}
+			if f.slots != nil {
+				select {
+				case f.slots <- struct{}{}:
+					defer func() { <-f.slots }()
+				case <-req.ctx.Done():
+					return fmt.Errorf("failed to wait for a free execution slot: %w", context.Cause(req.ctx))
+				}
+			}
+			return next(req, s)
+		}
+	}))
+}
+
+func (p *Policy) check(cmd string, args []string) error {
+	if p.RequireAbsolutePath && !filepath.IsAbs(cmd) {
+		return fmt.Errorf("%w: %q is not an absolute path", ErrPolicyViolation, cmd)
+	}
+	if len(p.AllowedCommands) > 0 && !slices.ContainsFunc(p.AllowedCommands, func(allowed string) bool {
+		if allowed == cmd {
+			return true
+		}
+		if strings.ContainsAny(allowed, `/\`) {
+			return false
+		}
+		// Paths only match the executable PATH resolves a bare entry to, not
+		// any executable sharing its name.
+		resolved, err := exec.LookPath(allowed)
+		return err == nil && resolved == cmd
+	}) {
+		return fmt.Errorf("%w: %q is not an allowed command", ErrPolicyViolation, cmd)
+	}
+	for index, arg := range args {
+		for _, pattern := range p.ForbiddenArgs {
+			if pattern.MatchString(arg) {
+				return fmt.Errorf("%w: argument %d matches forbidden pattern %q", ErrPolicyViolation, index, pattern)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestPolicyFactory(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := command.Policy{
+		AllowedCommands: []string{"git", "sh", "/usr/bin/ls"},
+		ForbiddenArgs:   []*regexp.Regexp{regexp.MustCompile(`^--force`)},
+	}
+	tests := []struct {
+		cmd     string
+		args    []string
+		allowed bool
+	}{
+		{cmd: "git", args: []string{"push"}, allowed: true},
+		{cmd: sh, allowed: true},
+		{cmd: "/usr/bin/ls", allowed: true},
+		{cmd: "/opt/evil/git", args: []string{"push"}},
+		{cmd: "./git", args: []string{"push"}},
+		{cmd: "ls"},
+		{cmd: "rm", args: []string{"-rf"}},
+		{cmd: "git", args: []string{"push", "--force-with-lease"}},
+	}
+	for _, test := range tests {
+		log := newRunLog(t)
+		factory := command.NewPolicyFactory(countingFactory{log}, policy)
+		err := factory.Command(context.Background(), test.cmd, test.args...).Run()
+		if test.allowed && err != nil {
+			t.Errorf("%s %q: unexpected error: %v", test.cmd, test.args, err)
+		}
+		if !test.allowed && (!errors.Is(err, command.ErrPolicyViolation) || log.runs() != 0) {
+			t.Errorf("%s %q: expected a policy violation, got %v after %d executions", test.cmd, test.args, err, log.runs())
+		}
+	}
+}
+
+func TestPolicyFactoryChecksDerivedCommands(t *testing.T) {
+	log := newRunLog(t)
+	policy := command.Policy{ForbiddenArgs: []*regexp.Regexp{regexp.MustCompile(`^--force$`)}}
+	r := command.NewPolicyFactory(countingFactory{log}, policy).Command(context.Background(), "git", "push")
+	if err := r.With(command.WithArgs("--force")).Run(); !errors.Is(err, command.ErrPolicyViolation) || log.runs() != 0 {
+		t.Errorf("expected arguments added later to be checked, got %v after %d executions", err, log.runs())
+	}
+}
+
+func TestPolicyFactoryChecksTheExecutedCommand(t *testing.T) {
+	policy := command.Policy{AllowedCommands: []string{"git"}}
+	err := command.NewPolicyFactory(command.NewShellKindCmdFactory(command.ShellSh), policy).Command(context.Background(), "git status").Run()
+	if !errors.Is(err, command.ErrPolicyViolation) {
+		t.Errorf("expected the shell to be rejected, got %v", err)
+	}
+}
+
+func TestPolicyFactoryRequireAbsolutePath(t *testing.T) {
+	log := newRunLog(t)
+	factory := command.NewPolicyFactory(countingFactory{log}, command.Policy{RequireAbsolutePath: true})
+	if err := factory.Command(context.Background(), "ls").Run(); !errors.Is(err, command.ErrPolicyViolation) {
+		t.Errorf("expected a relative command to be rejected, got %v", err)
+	}
+	if err := factory.Command(context.Background(), "/bin/ls").Run(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPolicyFactoryMaxConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	events, release := filepath.Join(dir, "events"), filepath.Join(dir, "release")
+	factory := command.NewPolicyFactory(command.NewExecCmdFactory(), command.Policy{MaxConcurrency: 2})
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			script := `echo + >> "$0"; until [ -e "$1" ]; do sleep 0.01; done; echo - >> "$0"`
+			_ = factory.Command(context.Background(), "sh", "-c", script, events, release).Run()
+		}()
+	}
+	for started := 0; started < 2; {
+		time.Sleep(10 * time.Millisecond)
+		content, _ := os.ReadFile(events)
+		started = strings.Count(string(content), "+")
+	}
+	if err := os.WriteFile(release, nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wg.Wait()
+	content, _ := os.ReadFile(events)
+	running, peak := 0, 0
+	for _, event := range strings.Fields(string(content)) {
+		if event == "+" {
+			running++
+		} else {
+			running--
+		}
+		peak = max(peak, running)
+	}
+	if peak != 2 {
+		t.Errorf("expected at most 2 concurrent commands, got %d", peak)
+	}
+}
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrConditionNotMet = errors.New("condition not met")
+
+type pollConfig struct {
+	maxAttempts int
+}
+
+type PollOption func(*pollConfig)
+
+// PollMaxAttempts bounds the number of executions, by default Poll keeps
+// running until its context is done.
+func PollMaxAttempts(attempts int) PollOption {
+	return func(c *pollConfig) {
+		c.maxAttempts = attempts
+	}
+}
+
+// Poll executes r every interval until the result satisfies until. Failed
+// executions are handed to until like successful ones as commands commonly
+// fail while waiting for a resource to exist, errors that prevent r from
+// running or exiting on its own, like a missing executable, end the polling.
+// Executions are stopped when ctx is done, the last
+// result being returned on failure. Poll waits with the clock of r.
+func Poll(ctx context.Context, r Runnable, interval time.Duration, until func(*Result) bool, opts ...PollOption) (*Result, error) {
+	var config pollConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	run := r.With(withCancel(ctx))
+	clock := clockOf(run)
+	var result *Result
+	var err error
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			return result, errors.Join(fmt.Errorf("%w: %w", ErrConditionNotMet, context.Cause(ctx)), err)
+		}
+		result, err = run.Execute()
+		if _, exited := ExitCode(err); !exited && ctx.Err() == nil {
+			return result, err
+		}
+		if until(result) {
+			return result, nil
+		}
+		if config.maxAttempts > 0 && attempt >= config.maxAttempts {
+			return result, errors.Join(fmt.Errorf("%w after %d attempts", ErrConditionNotMet, attempt), err)
+		}
+		timer := clock.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, errors.Join(fmt.Errorf("%w: %w", ErrConditionNotMet, context.Cause(ctx)), err)
+		case <-timer.C():
+		}
+	}
+}
+
+// withCancel stops the execution when ctx is done, in addition to the
+// context of the command itself.
+func withCancel(ctx context.Context) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			runCtx, cancel := context.WithCancelCause(req.ctx)
+			defer cancel(nil)
+			stop := context.AfterFunc(ctx, func() {
+				cancel(context.Cause(ctx))
+			})
+			defer stop()
+			bound := *req
+			bound.ctx = runCtx
+			return next(&bound, s)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func succeeded(result *command.Result) bool {
+	return result.ExitCode == 0
+}
+
+func TestPollUntilConditionMet(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	r, attempts := flakyCommand(t, 2, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := command.Poll(context.Background(), r.With(command.WithClock(clock)), time.Minute, succeeded)
+		done <- err
+	}()
+	for range 2 {
+		clock.WaitForTimers(1)
+		clock.Advance(time.Minute)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts() != 3 {
+		t.Errorf("expected 3 executions, got %d", attempts())
+	}
+}
+
+func TestPollMaxAttempts(t *testing.T) {
+	r, attempts := flakyCommand(t, 5, 1)
+	result, err := command.Poll(context.Background(), r, time.Millisecond, succeeded, command.PollMaxAttempts(2))
+	if !errors.Is(err, command.ErrConditionNotMet) {
+		t.Errorf("expected ErrConditionNotMet, got %v", err)
+	}
+	if attempts() != 2 || result == nil || result.ExitCode != 1 {
+		t.Errorf("expected the last of 2 results, got %d executions and %+v", attempts(), result)
+	}
+}
+
+func TestPollStopsRunningCommandWithContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := command.Poll(ctx, command.NewExecCmdFactory().Command(context.Background(), "sleep", "3"), time.Millisecond, succeeded)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected Poll to stop with its context, returned after %s", elapsed)
+	}
+	if !errors.Is(err, command.ErrConditionNotMet) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected ErrConditionNotMet caused by the deadline, got %v", err)
+	}
+}
+
+func TestPollChecksContextBeforeRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, attempts := flakyCommand(t, 0, 0)
+	if _, err := command.Poll(ctx, r, time.Millisecond, succeeded); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if attempts() != 0 {
+		t.Errorf("expected no execution, ran %d times", attempts())
+	}
+}
+
+func TestPollStopsOnStartFailures(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "no-such-command-in-path")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := command.Poll(ctx, r, time.Millisecond, succeeded)
+	if !command.IsNotFound(err) || errors.Is(err, command.ErrConditionNotMet) {
+		t.Errorf("expected the start failure to end the polling, got %v", err)
+	}
+}
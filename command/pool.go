@@ -0,0 +1,39 @@
+package command
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Executions reuse the buffers they only need while running: the stderr tail
+// kept for errors and the capture of the Run methods returning strings, whose
+// content is copied out by the conversion. The tail reads the stderr pipe
+// itself, sparing the copy buffer exec would allocate. What remains of an
+// execution is mostly the exec.Cmd and the start of the process. Buffers grown
+// over maxPooledBuffer are dropped instead of being kept after a large output.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBuffer {
+		return
+	}
+	buffer.Reset()
+	bufferPool.Put(buffer)
+}
+
+var tailPool = sync.Pool{New: func() any { return &tailBuffer{limit: maxErrorStderrBytes} }}
+
+func getTail() *tailBuffer {
+	return tailPool.Get().(*tailBuffer)
+}
+
+func putTail(tail *tailBuffer) {
+	tail.data = tail.data[:0]
+	tailPool.Put(tail)
+}
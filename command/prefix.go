@@ -0,0 +1,131 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// PrefixWriter writes each line it receives to the underlying writer in a
+// single call, preceded by a prefix. Several PrefixWriter values can share a
+// writer safe for concurrent use, like os.Stdout, without mixing their lines.
+type PrefixWriter struct {
+	mu        sync.Mutex
+	writer    io.Writer
+	prefix    func(lineStart time.Time) string
+	clock     Clock
+	pending   []byte
+	lineStart time.Time
+}
+
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{writer: w, prefix: func(time.Time) string { return prefix }, clock: systemClock{}}
+}
+
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		if len(w.pending) == 0 {
+			w.lineStart = w.clock.Now()
+		}
+		index := bytes.IndexByte(p, '\n')
+		if index < 0 {
+			w.pending = append(w.pending, p...)
+			if len(w.pending) >= defaultMaxLineSize {
+				if err := w.flush(); err != nil {
+					return n, err
+				}
+			}
+			break
+		}
+		w.pending = append(w.pending, p[:index+1]...)
+		if err := w.flush(); err != nil {
+			return n, err
+		}
+		p = p[index+1:]
+	}
+	return n, nil
+}
+
+// Flush writes a pending incomplete line.
+func (w *PrefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+func (w *PrefixWriter) flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	line := append([]byte(w.prefix(w.lineStart)), w.pending...)
+	w.pending = w.pending[:0]
+	_, err := w.writer.Write(line)
+	return err
+}
+
+// WithOutputPrefix prefixes every line of stdout and stderr, an empty prefix
+// leaves the stream untouched.
+func WithOutputPrefix(stdoutPrefix string, stderrPrefix string) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			return withLineWriters(s, func(w io.Writer, stream OutputStream) flushWriter {
+				prefix := stdoutPrefix
+				if stream == StreamStderr {
+					prefix = stderrPrefix
+				}
+				if prefix == "" {
+					return nil
+				}
+				return NewPrefixWriter(w, prefix)
+			}, func() error {
+				return next(req, s)
+			})
+		}
+	})
+}
+
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// withLineWriters runs fn with the streams wrapped by the writers returned by
+// wrap, nil keeping a stream as is, and flushes them once fn returns.
+func withLineWriters(s *streams, wrap func(w io.Writer, stream OutputStream) flushWriter, fn func() error) error {
+	stdout, stderr := s.stdout, s.stderr
+	defer func() { s.stdout, s.stderr = stdout, stderr }()
+	var shared io.Writer
+	if sameWriter(stdout, stderr) && stdout != nil {
+		shared = &lockedWriter{writer: stdout}
+	}
+	var wrapped []flushWriter
+	for _, stream := range []struct {
+		target *io.Writer
+		kind   OutputStream
+	}{{&s.stdout, StreamStdout}, {&s.stderr, StreamStderr}} {
+		if *stream.target == nil {
+			continue
+		}
+		w := *stream.target
+		if shared != nil {
+			w = shared
+		}
+		if writer := wrap(w, stream.kind); writer != nil {
+			wrapped = append(wrapped, writer)
+			*stream.target = writer
+		} else if shared != nil {
+			*stream.target = shared
+		}
+	}
+	err := fn()
+	for _, writer := range wrapped {
+		if flushErr := writer.Flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
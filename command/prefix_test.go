@@ -0,0 +1,56 @@
+package command_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := command.NewPrefixWriter(&out, "> ")
+	for _, write := range []string{"a\nb", "c\n", "\nd"} {
+		if _, err := writer.Write([]byte(write)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if out.String() != "> a\n> bc\n> \n" {
+		t.Errorf("expected complete lines only, got %q", out.String())
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "> a\n> bc\n> \n> d" {
+		t.Errorf("expected the pending line to be flushed, got %q", out.String())
+	}
+}
+
+func TestWithOutputPrefix(t *testing.T) {
+	r := streamsCommand("one\n", "!two\n", "three").With(command.WithOutputPrefix("out: ", "err: "))
+	var stdout, stderr bytes.Buffer
+	if err := r.RunToWriter(&stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "out: one\nout: three" || stderr.String() != "err: two\n" {
+		t.Errorf("unexpected output %q and %q", stdout.String(), stderr.String())
+	}
+	combined, err := r.RunCombinedStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if combined != "out: one\nerr: two\nout: three" {
+		t.Errorf("unexpected combined output %q", combined)
+	}
+}
+
+func TestWithOutputPrefixLeavesUnprefixedStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := streamsCommand("one\n", "!two\n").With(command.WithOutputPrefix("", "err: ")).RunToWriter(&stdout, &stderr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "one\n" || stderr.String() != "err: two\n" {
+		t.Errorf("unexpected output %q and %q", stdout.String(), stderr.String())
+	}
+}
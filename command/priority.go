@@ -0,0 +1,39 @@
+package command
+
+import "fmt"
+
+type IOPriorityClass int
+
+const (
+	IOPriorityRealtime IOPriorityClass = iota + 1
+	IOPriorityBestEffort
+	IOPriorityIdle
+)
+
+// WithNice sets the scheduling priority of the started process, it has no
+// effect on platforms without process niceness. As WithRLimits, the priority
+// is set right after the process starts, the process running with the one of
+// the current process until then.
+func WithNice(level int) Option {
+	return func(r *commandRequest) {
+		r.nice = &level
+	}
+}
+
+// WithIOPriority sets the I/O scheduling class and level, from 0 to 7, of the
+// started process, right after it starts as WithNice. It is only applied on
+// Linux.
+func WithIOPriority(class IOPriorityClass, level int) Option {
+	return func(r *commandRequest) {
+		if class < IOPriorityRealtime || class > IOPriorityIdle || level < 0 || level > 7 {
+			r.err = fmt.Errorf("invalid I/O priority class %d level %d", class, level)
+			return
+		}
+		r.ioPriority = &ioPriority{class: class, level: level}
+	}
+}
+
+type ioPriority struct {
+	class IOPriorityClass
+	level int
+}
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"fmt"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+func applyPriority(req *commandRequest, pid int) error {
+	if req.nice != nil {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, *req.nice); err != nil {
+			return fmt.Errorf("failed to set nice level %d: %w", *req.nice, err)
+		}
+	}
+	if req.ioPriority != nil {
+		value := int(req.ioPriority.class)<<ioprioClassShift | req.ioPriority.level
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(value))
+		if errno != 0 {
+			return fmt.Errorf("failed to set I/O priority: %w", errno)
+		}
+	}
+	return nil
+}
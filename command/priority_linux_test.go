@@ -0,0 +1,53 @@
+package command_test
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// startedOutput runs script once the process is started, which is when the
+// options applied after the start have already landed.
+func startedOutput(t *testing.T, script string, opts ...command.Option) string {
+	t.Helper()
+	reader, writer := io.Pipe()
+	opts = append(opts, command.WithStdinReader(reader))
+	process, err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "read _; "+script).With(opts...).Start()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = io.WriteString(writer, "\n")
+	_ = writer.Close()
+	if err := process.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.TrimSpace(string(process.Stdout()))
+}
+
+func TestWithNice(t *testing.T) {
+	if out := startedOutput(t, "nice", command.WithNice(7)); out != "7" {
+		t.Errorf("expected the nice level to be 7, got %q", out)
+	}
+}
+
+func TestWithIOPriority(t *testing.T) {
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice is not available")
+	}
+	out := startedOutput(t, "ionice -p $$", command.WithIOPriority(command.IOPriorityBestEffort, 6))
+	if out != "best-effort: prio 6" {
+		t.Errorf("unexpected I/O priority %q", out)
+	}
+}
+
+func TestWithIOPriorityRejectsInvalidLevels(t *testing.T) {
+	err := command.NewExecCmdFactory().Command(context.Background(), "true").
+		With(command.WithIOPriority(command.IOPriorityIdle, 8)).Run()
+	if err == nil || !strings.Contains(err.Error(), "invalid I/O priority") {
+		t.Errorf("expected an invalid priority error, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package command
+
+func applyPriority(*commandRequest, int) error {
+	return nil
+}
@@ -0,0 +1,149 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+var ErrProcessNotStarted = errors.New("process not started")
+
+// ErrProcessNotRunning is returned when signalling the Process of a command
+// retried by WithRetry between two attempts, once one exited and before the
+// next one started.
+var ErrProcessNotRunning = errors.New("process not running")
+
+// Process is a started execution, its methods can be called from any
+// goroutine. Once the execution is over the signalling methods return
+// os.ErrProcessDone, ErrProcessNotStarted when called before the process
+// started and ErrProcessNotRunning between attempts.
+type Process interface {
+	// Wait can be called any number of times, always returning the error of
+	// the execution.
+	Wait() error
+	Signal(sig os.Signal) error
+	// Interrupt sends SIGINT, or CTRL_BREAK on Windows.
+	Interrupt() error
+	// Terminate sends SIGTERM, Windows kills the process as it has no
+	// equivalent.
+	Terminate() error
+	Kill() error
+	Pid() int
+	Done() <-chan struct{}
+	// Stdout and Stderr return the output written so far, all of it once
+	// Done is closed.
+	Stdout() []byte
+	Stderr() []byte
+}
+
+type processState int
+
+const (
+	processStarting processState = iota
+	processRunning
+	// processExited is an attempt that exited, WithRetry may start another.
+	processExited
+	processDone
+)
+
+type execProcess struct {
+	mu      sync.Mutex
+	state   processState
+	current *os.Process
+	signal  func(os.Signal) error
+	stdout  syncBuffer
+	stderr  syncBuffer
+	done    chan struct{}
+	err     error
+}
+
+func (e *execCommand) Start() (Process, error) {
+	process := &execProcess{done: make(chan struct{})}
+	started := make(chan struct{})
+	var startOnce sync.Once
+	s := captureStreams(nil, &process.stdout, &process.stderr)
+	s.onStart = func(current *os.Process, signal func(os.Signal) error) {
+		process.mu.Lock()
+		process.state = processRunning
+		process.current = current
+		process.signal = signal
+		process.mu.Unlock()
+		startOnce.Do(func() { close(started) })
+	}
+	s.onExit = func() {
+		process.mu.Lock()
+		if process.state == processRunning {
+			process.state = processExited
+		}
+		process.mu.Unlock()
+	}
+	go func() {
+		err := e.run(s)
+		process.mu.Lock()
+		process.state = processDone
+		process.err = err
+		process.mu.Unlock()
+		close(process.done)
+	}()
+	select {
+	case <-started:
+		return process, nil
+	case <-process.done:
+		if process.err != nil && process.Pid() == 0 {
+			return nil, process.err
+		}
+		return process, nil
+	}
+}
+
+func (p *execProcess) Wait() error {
+	<-p.done
+	return p.err
+}
+
+func (p *execProcess) Signal(sig os.Signal) error {
+	return p.whileRunning(func() error {
+		return p.signal(sig)
+	})
+}
+
+// whileRunning calls signal holding the lock, so the execution cannot be
+// marked as exited while the signal is sent.
+func (p *execProcess) whileRunning(signal func() error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.state {
+	case processStarting:
+		return ErrProcessNotStarted
+	case processExited:
+		return ErrProcessNotRunning
+	case processDone:
+		return os.ErrProcessDone
+	}
+	return signal()
+}
+
+func (p *execProcess) Kill() error {
+	return p.Signal(os.Kill)
+}
+
+func (p *execProcess) Pid() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current == nil {
+		return 0
+	}
+	return p.current.Pid
+}
+
+func (p *execProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *execProcess) Stdout() []byte {
+	return p.stdout.Bytes()
+}
+
+func (p *execProcess) Stderr() []byte {
+	return p.stderr.Bytes()
+}
@@ -0,0 +1,174 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestStart(t *testing.T) {
+	process, err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo out; echo err >&2").Start()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if process.Pid() <= 0 {
+		t.Errorf("expected the pid of the started process, got %d", process.Pid())
+	}
+	if err := process.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-process.Done()
+	if string(process.Stdout()) != "out\n" || string(process.Stderr()) != "err\n" {
+		t.Errorf("unexpected output %q and %q", process.Stdout(), process.Stderr())
+	}
+	if err := process.Signal(os.Interrupt); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("expected os.ErrProcessDone once exited, got %v", err)
+	}
+}
+
+func TestStartReportsStartFailures(t *testing.T) {
+	process, err := command.NewExecCmdFactory().Command(context.Background(), "no-such-command-in-path").Start()
+	if err == nil || process != nil {
+		t.Fatalf("expected the start to fail, got %v", err)
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestConcurrentUseOfRunnableAndProcess(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `echo "$VALUE"`).
+		With(command.WithEnv(map[string]string{"VALUE": "base"}))
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value := fmt.Sprint(i)
+			derived := r.With(command.WithEnv(map[string]string{"VALUE": value}))
+			if output, err := derived.RunStdoutStr(); err != nil || output != value+"\n" {
+				t.Errorf("derived command: unexpected output %q and error %v", output, err)
+			}
+			if output, err := r.RunStdoutStr(); err != nil || output != "base\n" {
+				t.Errorf("shared command: unexpected output %q and error %v", output, err)
+			}
+			process, err := r.Start()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if err := process.Wait(); err != nil || string(process.Stdout()) != "base\n" {
+				t.Errorf("started command: unexpected output %q and error %v", process.Stdout(), err)
+			}
+		}()
+	}
+
+	process, err := command.NewExecCmdFactory().Command(context.Background(), "sleep", "10").Start()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := process.Signal(syscall.Signal(0))
+				_ = process.Pid()
+				_ = process.Stdout()
+				if errors.Is(err, os.ErrProcessDone) {
+					return
+				}
+				if err != nil {
+					t.Errorf("unexpected signal error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	if err := process.Kill(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := process.Wait(); err == nil {
+		t.Error("expected the killed process to fail")
+	}
+	wg.Wait()
+}
+
+func TestProcessBetweenRetryAttempts(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "exit 1").With(command.WithClock(clock))
+	process, err := command.WithRetry(r, command.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour}).Start()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.WaitForTimers(1)
+	if err := process.Signal(os.Interrupt); !errors.Is(err, command.ErrProcessNotRunning) {
+		t.Errorf("expected ErrProcessNotRunning between attempts, got %v", err)
+	}
+	clock.Advance(time.Hour)
+	if code, _ := command.ExitCode(process.Wait()); code != 1 {
+		t.Errorf("expected the exit code of the last attempt, got %v", process.Wait())
+	}
+	if err := process.Kill(); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("expected os.ErrProcessDone, got %v", err)
+	}
+}
+
+// startReady starts script, which prints ready once set up, and waits for it.
+func startReady(t *testing.T, script string) command.Process {
+	t.Helper()
+	process, err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", script).Start()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(string(process.Stdout()), "ready\n") {
+		if time.Now().After(deadline) {
+			t.Fatal("the command did not get ready")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return process
+}
+
+func TestProcessInterrupt(t *testing.T) {
+	process := startReady(t, `trap 'echo interrupted; exit 3' INT; echo ready; while :; do sleep 0.01; done`)
+	if err := process.Interrupt(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cmdErr *command.CommandError
+	if err := process.Wait(); !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 3 {
+		t.Errorf("expected the exit code of the trap, got %v", err)
+	}
+	if !strings.HasSuffix(string(process.Stdout()), "interrupted\n") {
+		t.Errorf("unexpected output %q", process.Stdout())
+	}
+}
+
+func TestProcessTerminateAndKill(t *testing.T) {
+	for name, stop := range map[string]func(command.Process) error{
+		"terminate": command.Process.Terminate,
+		"kill":      command.Process.Kill,
+	} {
+		process := startReady(t, "echo ready; exec sleep 30")
+		if err := stop(process); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var cmdErr *command.CommandError
+		if err := process.Wait(); !errors.As(err, &cmdErr) || cmdErr.ExitCode() >= 0 {
+			t.Errorf("%s: expected the command to be stopped by a signal, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+func WithProcessGroup() Option {
+	return func(r *commandRequest) {
+		r.processGroup = true
+	}
+}
+
+type processGroup struct {
+	cmd        *exec.Cmd
+	clock      Clock
+	mu         sync.Mutex
+	handle     groupHandle
+	escalation Timer
+}
+
+func newProcessGroup(cmd *exec.Cmd, clock Clock) *processGroup {
+	group := &processGroup{cmd: cmd, clock: clock}
+	group.prepare()
+	return group
+}
+
+func (g *processGroup) stop(sig os.Signal, gracePeriod time.Duration) error {
+	if sig == nil || sig == os.Kill {
+		return g.signal(os.Kill)
+	}
+	err := g.signal(sig)
+	if gracePeriod > 0 {
+		g.mu.Lock()
+		if g.escalation == nil {
+			g.escalation = g.clock.AfterFunc(gracePeriod, func() {
+				_ = g.signal(os.Kill)
+			})
+		}
+		g.mu.Unlock()
+	}
+	return err
+}
+
+func (g *processGroup) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.escalation != nil {
+		g.escalation.Stop()
+	}
+	g.close()
+}
@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+package command
+
+import "os"
+
+type groupHandle struct{}
+
+func (g *processGroup) prepare() {}
+
+func (g *processGroup) attach() error {
+	return nil
+}
+
+func (g *processGroup) signal(sig os.Signal) error {
+	return g.cmd.Process.Signal(sig)
+}
+
+func (g *processGroup) close() {}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package command
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+type groupHandle struct{}
+
+func (g *processGroup) prepare() {
+	if g.cmd.SysProcAttr == nil {
+		g.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// A new session already makes the process the leader of a new group.
+	if !g.cmd.SysProcAttr.Setsid {
+		g.cmd.SysProcAttr.Setpgid = true
+	}
+}
+
+func (g *processGroup) attach() error {
+	return nil
+}
+
+func (g *processGroup) signal(sig os.Signal) error {
+	unixSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return g.cmd.Process.Signal(sig)
+	}
+	// The group leader is the started process, its pid is the group id.
+	err := syscall.Kill(-g.cmd.Process.Pid, unixSig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+func (g *processGroup) close() {}
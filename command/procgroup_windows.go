@@ -0,0 +1,92 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type groupHandle struct {
+	job windows.Handle
+}
+
+// prepare creates the process suspended, so it cannot start children before
+// it is assigned to the job, attach resuming it afterwards.
+func (g *processGroup) prepare() {
+	if g.cmd.SysProcAttr == nil {
+		g.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	g.cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+}
+
+func (g *processGroup) attach() error {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(g.cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	defer windows.CloseHandle(process)
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	g.mu.Lock()
+	g.handle.job = job
+	g.mu.Unlock()
+	return resumeProcess(uint32(g.cmd.Process.Pid))
+}
+
+// resumeProcess resumes the threads of a process created suspended, exec not
+// keeping the handle of its main thread.
+func resumeProcess(pid uint32) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != pid {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return err
+		}
+		_, err = windows.ResumeThread(thread)
+		windows.CloseHandle(thread)
+		if err != nil {
+			return err
+		}
+	}
+	if errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return nil
+	}
+	return err
+}
+
+// signal terminates the whole job, Windows has no way to deliver other
+// signals to a set of processes.
+func (g *processGroup) signal(_ os.Signal) error {
+	g.mu.Lock()
+	job := g.handle.job
+	g.mu.Unlock()
+	if job == 0 {
+		return g.cmd.Process.Kill()
+	}
+	return windows.TerminateJobObject(job, 1)
+}
+
+func (g *processGroup) close() {
+	if g.handle.job != 0 {
+		windows.CloseHandle(g.handle.job)
+		g.handle.job = 0
+	}
+}
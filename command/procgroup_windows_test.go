@@ -0,0 +1,28 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithProcessGroupStopsDescendants(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// The background ping inherits the stderr pipe, without the job object
+	// Run would wait for its 30 seconds.
+	err := command.NewShellKindCmdFactory(command.ShellCmd).
+		Command(ctx, "start /b ping -n 30 127.0.0.1 & ping -n 30 127.0.0.1").
+		With(command.WithProcessGroup()).
+		Run()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the command to stop with its descendants, it stopped after %s", elapsed)
+	}
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var errNilProgress = errors.New("nil progress callback")
+
+// WithProgressParser calls onProgress for each line of stdout or stderr
+// matching pattern, whose first group, or whole match without groups, holds
+// the percentage. Lines are also split on carriage returns, which is how most
+// tools redraw their progress. Calls are never concurrent. A nil onProgress
+// makes the command fail.
+func WithProgressParser(pattern string, onProgress func(percent float64, line string)) Option {
+	compiled, err := regexp.Compile(pattern)
+	return func(r *commandRequest) {
+		if err != nil {
+			r.err = fmt.Errorf("invalid progress pattern: %w", err)
+			return
+		}
+		if onProgress == nil {
+			r.err = errNilProgress
+			return
+		}
+		withMiddleware(func(next execFunc) execFunc {
+			return func(req *commandRequest, s *streams) error {
+				stdout, stderr := s.stdout, s.stderr
+				defer func() { s.stdout, s.stderr = stdout, stderr }()
+				var mu sync.Mutex
+				stdoutProgress := &progressWriter{mu: &mu, pattern: compiled, onProgress: onProgress}
+				stderrProgress := &progressWriter{mu: &mu, pattern: compiled, onProgress: onProgress}
+				s.tee(stdoutProgress, stderrProgress)
+				err := next(req, s)
+				stdoutProgress.flush()
+				stderrProgress.flush()
+				return err
+			}
+		})(r)
+	}
+}
+
+type progressWriter struct {
+	mu         *sync.Mutex
+	pattern    *regexp.Regexp
+	onProgress func(percent float64, line string)
+	pending    []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		index := bytes.IndexAny(p, "\r\n")
+		if index < 0 {
+			w.pending = append(w.pending, p...)
+			if len(w.pending) > defaultMaxLineSize {
+				w.pending = w.pending[len(w.pending)-defaultMaxLineSize:]
+			}
+			break
+		}
+		w.pending = append(w.pending, p[:index]...)
+		w.flush()
+		p = p[index+1:]
+	}
+	return n, nil
+}
+
+func (w *progressWriter) flush() {
+	line := string(w.pending)
+	w.pending = w.pending[:0]
+	match := w.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	value := match[0]
+	if len(match) > 1 {
+		value = match[1]
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")), 64)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onProgress(percent, line)
+}
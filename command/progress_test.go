@@ -0,0 +1,45 @@
+package command_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithProgressParser(t *testing.T) {
+	var percents []float64
+	var lines []string
+	out, err := streamsCommand("downloading\r 10%\r 55.5%\r", "!100% done\n", "finished").
+		With(command.WithProgressParser(`(\d+(?:\.\d+)?)%`, func(percent float64, line string) {
+			percents = append(percents, percent)
+			lines = append(lines, line)
+		})).
+		RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(percents, []float64{10, 55.5, 100}) {
+		t.Errorf("unexpected progress %v", percents)
+	}
+	if lines[2] != "100% done" {
+		t.Errorf("expected the matching line, got %q", lines)
+	}
+	if out != "downloading\r 10%\r 55.5%\rfinished" {
+		t.Errorf("expected the output to be left untouched, got %q", out)
+	}
+}
+
+func TestWithProgressParserRejectsInvalidPatterns(t *testing.T) {
+	err := outputCommand("").With(command.WithProgressParser(`(`, func(float64, string) {})).Run()
+	if err == nil || !strings.Contains(err.Error(), "invalid progress pattern") {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+}
+
+func TestWithProgressParserRejectsNilCallbacks(t *testing.T) {
+	if err := outputCommand("").With(command.WithProgressParser(`(\d+)%`, nil)).Run(); err == nil {
+		t.Error("expected a nil callback to fail")
+	}
+}
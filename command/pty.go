@@ -0,0 +1,23 @@
+package command
+
+// WithPTY runs the command attached to a pseudo terminal. The terminal merges
+// stdout and stderr into a single stream written to the stdout writer, stdin
+// is forwarded to the terminal input.
+func WithPTY() Option {
+	return func(r *commandRequest) {
+		if r.pty == nil {
+			r.pty = &ptySize{}
+		}
+	}
+}
+
+func WithPTYSize(rows, cols uint16) Option {
+	return func(r *commandRequest) {
+		r.pty = &ptySize{rows: rows, cols: cols}
+	}
+}
+
+type ptySize struct {
+	rows uint16
+	cols uint16
+}
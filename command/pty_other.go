@@ -0,0 +1,21 @@
+//go:build !unix
+
+package command
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+type terminal struct{}
+
+func openTerminal(*exec.Cmd, *ptySize) (*terminal, error) {
+	return nil, errors.New("pseudo terminals are not supported on this platform")
+}
+
+func (t *terminal) started() {}
+
+func (t *terminal) wait(context.Context) {}
+
+func (t *terminal) close() {}
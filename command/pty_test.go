@@ -0,0 +1,72 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithPTY(t *testing.T) {
+	r := command.NewExecCmdFactory().
+		Command(context.Background(), "sh", "-c", "test -t 0 && test -t 1 && test -t 2 && echo terminal; stty size").
+		With(command.WithPTYSize(24, 100))
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "terminal\r\n24 100\r\n" {
+		t.Errorf("expected the streams on a 24x100 terminal, got %q", output)
+	}
+}
+
+func TestWithPTYForwardsStdin(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `read line; echo "got $line"`).With(command.WithPTY())
+	var output strings.Builder
+	if err := r.RunIO(strings.NewReader("input\n"), &output, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output.String(), "got input\r\n") {
+		t.Errorf("expected the input to reach the command, got %q", output.String())
+	}
+}
+
+func TestWithPTYDoesNotWaitForDescendants(t *testing.T) {
+	// The background sleep ignores the hang up of the terminal and keeps it
+	// open, stdin is never closed.
+	stdin, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	r := command.NewExecCmdFactory().
+		Command(context.Background(), "sh", "-c", "(trap '' HUP; exec sleep 30) & echo done").
+		With(command.WithPTY())
+	var output strings.Builder
+	start := time.Now()
+	if err := r.RunIO(stdin, &output, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected Run to return once the command exited, it took %s", elapsed)
+	}
+	if output.String() != "done\r\n" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
+
+func TestWithPTYStopsOnTimeout(t *testing.T) {
+	r := command.NewExecCmdFactory().
+		Command(context.Background(), "sh", "-c", "(trap '' HUP; exec sleep 30) & exec sleep 30").
+		With(command.WithPTY(), command.WithTimeout(100*time.Millisecond))
+	start := time.Now()
+	if err := r.Run(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to stop on timeout, it took %s", elapsed)
+	}
+}
@@ -0,0 +1,113 @@
+//go:build unix
+
+package command
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// ptyDrainTimeout bounds the wait for the terminal output once the command
+// exited, which otherwise lasts as long as any descendant keeps the terminal
+// open.
+const ptyDrainTimeout = time.Second
+
+type terminal struct {
+	ptmx      *os.File
+	tty       *os.File
+	stdin     io.Reader
+	output    io.Writer
+	copied    chan struct{}
+	closeOnce sync.Once
+}
+
+func openTerminal(cmd *exec.Cmd, size *ptySize) (*terminal, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, err
+	}
+	if size.rows > 0 || size.cols > 0 {
+		if err := pty.Setsize(ptmx, &pty.Winsize{Rows: size.rows, Cols: size.cols}); err != nil {
+			ptmx.Close()
+			tty.Close()
+			return nil, err
+		}
+	}
+	if ptmx, err = pollable(ptmx); err != nil {
+		tty.Close()
+		return nil, err
+	}
+	term := &terminal{ptmx: ptmx, tty: tty, stdin: cmd.Stdin, output: cmd.Stdout, copied: make(chan struct{})}
+	if term.output == nil {
+		term.output = io.Discard
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	return term, nil
+}
+
+// pollable returns a non blocking copy of file, so closing it interrupts the
+// reads in progress. pty.Open hands out the master in blocking mode, where a
+// read lasts until some output comes.
+func pollable(file *os.File) (*os.File, error) {
+	defer file.Close()
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), file.Name()), nil
+}
+
+func (t *terminal) started() {
+	t.tty.Close()
+	if t.stdin != nil {
+		// Like the stdin copy of exec, this ends at the next read from stdin
+		// once the terminal is closed, writing then fails.
+		go func() {
+			_, _ = io.Copy(t.ptmx, t.stdin)
+		}()
+	}
+	go func() {
+		defer close(t.copied)
+		// Reading ends with EIO on Linux once every terminal descriptor held by
+		// the child is closed, that is the expected end of the stream.
+		_, _ = io.Copy(t.output, t.ptmx)
+	}()
+}
+
+// wait drains the output of the exited command. Descendants keeping the
+// terminal open only delay it by ptyDrainTimeout, and not at all once ctx is
+// done: the terminal is closed then, ending the output copy.
+func (t *terminal) wait(ctx context.Context) {
+	timer := time.NewTimer(ptyDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-t.copied:
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	t.close()
+	<-t.copied
+}
+
+func (t *terminal) close() {
+	t.closeOnce.Do(func() {
+		t.ptmx.Close()
+		t.tty.Close()
+	})
+}
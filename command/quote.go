@@ -0,0 +1,71 @@
+package command
+
+import "strings"
+
+func isPosixSafe(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		strings.ContainsRune("_@%+=:,./-", r)
+}
+
+// Quote returns arg quoted for a POSIX shell, arguments made only of safe
+// characters are returned unchanged.
+func Quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if strings.IndexFunc(arg, func(r rune) bool { return !isPosixSafe(r) }) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func Join(args ...string) string {
+	quoted := make([]string, len(args))
+	for index, arg := range args {
+		quoted[index] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// QuoteWindows returns arg quoted following the rules used by
+// CommandLineToArgvW and the Microsoft C runtime.
+func QuoteWindows(arg string) string {
+	return quoteWindows(arg, false)
+}
+
+func JoinWindows(args ...string) string {
+	quoted := make([]string, len(args))
+	for index, arg := range args {
+		quoted[index] = QuoteWindows(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quoteWindows(arg string, force bool) string {
+	if arg == "" {
+		return `""`
+	}
+	if !force && !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+	var builder strings.Builder
+	builder.WriteByte('"')
+	backslashes := 0
+	for index := 0; index < len(arg); index++ {
+		switch c := arg[index]; c {
+		case '\\':
+			backslashes++
+		case '"':
+			builder.WriteString(strings.Repeat(`\`, backslashes*2+1))
+			builder.WriteByte(c)
+			backslashes = 0
+		default:
+			builder.WriteString(strings.Repeat(`\`, backslashes))
+			builder.WriteByte(c)
+			backslashes = 0
+		}
+	}
+	builder.WriteString(strings.Repeat(`\`, backslashes*2))
+	builder.WriteByte('"')
+	return builder.String()
+}
@@ -0,0 +1,44 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"":             "''",
+		"plain/path-1": "plain/path-1",
+		"two words":    "'two words'",
+		"it's":         `'it'\''s'`,
+		"$HOME":        "'$HOME'",
+	}
+	for arg, expected := range tests {
+		if quoted := command.Quote(arg); quoted != expected {
+			t.Errorf("Quote(%q): expected %s, got %s", arg, expected, quoted)
+		}
+	}
+	if line := command.Join("echo", "a b", "c"); line != "echo 'a b' c" {
+		t.Errorf("unexpected line %s", line)
+	}
+}
+
+func TestQuoteWindows(t *testing.T) {
+	tests := map[string]string{
+		"":             `""`,
+		`C:\dir\file`:  `C:\dir\file`,
+		"two words":    `"two words"`,
+		`say "hi"`:     `"say \"hi\""`,
+		`C:\my dir\`:   `"C:\my dir\\"`,
+		`back\\"quote`: `"back\\\\\"quote"`,
+	}
+	for arg, expected := range tests {
+		if quoted := command.QuoteWindows(arg); quoted != expected {
+			t.Errorf("QuoteWindows(%q): expected %s, got %s", arg, expected, quoted)
+		}
+	}
+	if line := command.JoinWindows("echo", "a b", "c"); line != `echo "a b" c` {
+		t.Errorf("unexpected line %s", line)
+	}
+}
@@ -0,0 +1,106 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	// Executions allowed per Interval, both must be positive.
+	Executions int
+	Interval   time.Duration
+	// Burst is the number of executions that can start at once after an idle
+	// period, Executions when zero.
+	Burst int
+}
+
+type rateLimitedFactory struct {
+	inner  CommandFactory
+	bucket *tokenBucket
+	err    error
+}
+
+// NewRateLimitedFactory returns a factory whose commands wait, shared across
+// all of them, until the limit allows them to run. The commands of an invalid
+// limit fail without running. The limit is measured with the clock of the
+// commands, which should all share the same one.
+func NewRateLimitedFactory(inner CommandFactory, limit RateLimit) CommandFactory {
+	if limit.Executions <= 0 || limit.Interval <= 0 || limit.Burst < 0 {
+		err := fmt.Errorf("invalid rate limit of %d executions per %s with a burst of %d", limit.Executions, limit.Interval, limit.Burst)
+		return &rateLimitedFactory{inner: inner, err: err}
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Executions
+	}
+	rate := float64(limit.Executions) / float64(limit.Interval)
+	return &rateLimitedFactory{
+		inner:  inner,
+		bucket: &tokenBucket{tokens: float64(burst), capacity: float64(burst), rate: rate},
+	}
+}
+
+func (f *rateLimitedFactory) Command(ctx context.Context, cmd string, args ...string) Runnable {
+	if f.err != nil {
+		return f.inner.Command(ctx, cmd, args...).With(withError(f.err))
+	}
+	return f.inner.Command(ctx, cmd, args...).With(withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			if err := f.bucket.wait(req.ctx, req.clockOrDefault()); err != nil {
+				return fmt.Errorf("failed to wait for the rate limit: %w", err)
+			}
+			return next(req, s)
+		}
+	}))
+}
+
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	// rate is the number of tokens added per nanosecond.
+	rate float64
+	// last is zero until the first reservation, the bucket starting full.
+	last time.Time
+}
+
+// reserve takes a token, possibly leaving the bucket in debt, and returns how
+// long to wait until the token is actually available.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.last.IsZero() {
+		b.last = now
+	}
+	b.tokens = min(b.capacity, b.tokens+float64(now.Sub(b.last))*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate)
+}
+
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+func (b *tokenBucket) wait(ctx context.Context, clock Clock) error {
+	delay := b.reserve(clock.Now())
+	if delay <= 0 {
+		return nil
+	}
+	timer := clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return context.Cause(ctx)
+	case <-timer.C():
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestRateLimitedFactory(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	log := newRunLog(t)
+	factory := clockedFactory{command.NewRateLimitedFactory(countingFactory{log}, command.RateLimit{Executions: 2, Interval: time.Second}), clock}
+	for range 2 {
+		if err := factory.Command(context.Background(), "list").Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- factory.Command(context.Background(), "list").Run()
+	}()
+	clock.WaitForTimers(1)
+	if log.runs() != 2 {
+		t.Fatalf("expected the third execution to wait, ran %d times", log.runs())
+	}
+	clock.Advance(500 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if log.runs() != 3 {
+		t.Errorf("expected 3 executions, ran %d times", log.runs())
+	}
+}
+
+func TestRateLimitedFactoryStopsWaitingWithContext(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	log := newRunLog(t)
+	factory := clockedFactory{command.NewRateLimitedFactory(countingFactory{log}, command.RateLimit{Executions: 1, Interval: time.Hour}), clock}
+	if err := factory.Command(context.Background(), "list").Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- factory.Command(ctx, "list").Run()
+	}()
+	clock.WaitForTimers(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the wait to be canceled, got %v", err)
+	}
+	if log.runs() != 1 {
+		t.Errorf("expected the canceled command not to run, ran %d times", log.runs())
+	}
+}
+
+func TestRateLimitedFactoryRejectsInvalidLimits(t *testing.T) {
+	for _, limit := range []command.RateLimit{
+		{Executions: 1},
+		{Interval: time.Second},
+		{Executions: 1, Interval: -time.Second},
+		{Executions: 1, Interval: time.Second, Burst: -1},
+	} {
+		log := newRunLog(t)
+		err := command.NewRateLimitedFactory(countingFactory{log}, limit).Command(context.Background(), "list").Run()
+		if err == nil || !strings.Contains(err.Error(), "invalid rate limit") || log.runs() != 0 {
+			t.Errorf("%+v: expected an invalid limit error, got %v after %d executions", limit, err, log.runs())
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package command
+
+import (
+	"bytes"
+	"time"
+)
+
+type Result struct {
+	Stdout []byte
+	Stderr []byte
+	// ExitCode is -1 when the command failed without exiting on its own, for
+	// example when it could not be started or was killed.
+	ExitCode int
+	Duration time.Duration
+	// Truncated is set when captured output exceeded WithMaxOutputBytes.
+	Truncated bool
+}
+
+func (e *execCommand) Execute() (*Result, error) {
+	var stdout, stderr bytes.Buffer
+	clock := e.clockOrDefault()
+	start := clock.Now()
+	s := captureStreams(nil, &stdout, &stderr)
+	err := e.run(s)
+	result := &Result{
+		Stdout:    stdout.Bytes(),
+		Stderr:    stderr.Bytes(),
+		Duration:  clock.Now().Sub(start),
+		Truncated: s.truncated,
+	}
+	result.ExitCode, _ = ExitCode(err)
+	return result, err
+}
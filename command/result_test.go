@@ -0,0 +1,41 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestExecute(t *testing.T) {
+	factory := command.NewExecCmdFactory()
+	tests := []struct {
+		name     string
+		cmd      string
+		args     []string
+		exitCode int
+		stdout   string
+		stderr   string
+		fails    bool
+	}{
+		{name: "success", cmd: "sh", args: []string{"-c", "echo out; echo err >&2"}, stdout: "out\n", stderr: "err\n"},
+		{name: "exit code", cmd: "sh", args: []string{"-c", "echo out; exit 4"}, exitCode: 4, stdout: "out\n", fails: true},
+		{name: "not started", cmd: "no-such-command-in-path", exitCode: -1, fails: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := factory.Command(context.Background(), test.cmd, test.args...).Execute()
+			if (err != nil) != test.fails {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ExitCode != test.exitCode {
+				t.Errorf("expected exit code %d, got %d", test.exitCode, result.ExitCode)
+			}
+			if string(result.Stdout) != test.stdout || string(result.Stderr) != test.stderr {
+				t.Errorf("unexpected output %q and %q", result.Stdout, result.Stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"time"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts counts the first execution too, values below one run once.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier defaults to 2 when zero.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each backoff that is
+	// randomized.
+	Jitter float64
+	// RetryIf decides if a failed attempt should be retried, by default every
+	// CommandError is.
+	RetryIf func(err error) bool
+}
+
+func RetryOnExitCode(codes ...int) func(err error) bool {
+	return func(err error) bool {
+		var cmdErr *CommandError
+		return errors.As(err, &cmdErr) && slices.Contains(codes, cmdErr.ExitCode())
+	}
+}
+
+func RetryOnStderrContains(substr string) func(err error) bool {
+	return func(err error) bool {
+		var cmdErr *CommandError
+		return errors.As(err, &cmdErr) && bytes.Contains(cmdErr.Stderr(), []byte(substr))
+	}
+}
+
+func RetryOnStderrMatch(pattern *regexp.Regexp) func(err error) bool {
+	return func(err error) bool {
+		var cmdErr *CommandError
+		return errors.As(err, &cmdErr) && pattern.Match(cmdErr.Stderr())
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(err error) bool {
+	if p.RetryIf != nil {
+		return p.RetryIf(err)
+	}
+	var cmdErr *CommandError
+	return errors.As(err, &cmdErr)
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			delay = float64(p.MaxBackoff)
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// WithRetry re-runs failed executions of r following the given policy. Output
+// captured by the Run methods only holds the last attempt, writers supplied by
+// the caller receive the output of every attempt. Attempts are not repeated if
+// stdin cannot be rewound.
+func WithRetry(r Runnable, policy RetryPolicy) Runnable {
+	return r.With(withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			err := next(req, s)
+			for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+				if err == nil || !policy.shouldRetry(err) {
+					return err
+				}
+				timer := req.clockOrDefault().NewTimer(policy.backoff(attempt))
+				select {
+				case <-req.ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C():
+				}
+				if !s.rewind() {
+					return err
+				}
+				err = next(req, s)
+			}
+			return err
+		}
+	}))
+}
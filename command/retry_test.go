@@ -0,0 +1,90 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+// flakyCommand fails with exitCode until it ran failures times, each attempt
+// printing its number. The returned function counts the attempts.
+func flakyCommand(t *testing.T, failures int, exitCode int) (command.Runnable, func() int) {
+	log := newRunLog(t)
+	script := fmt.Sprintf(`echo "attempt $RUN"; [ "$RUN" -gt %d ] || exit %d`, failures, exitCode)
+	return log.command(context.Background(), "flaky", script), log.runs
+}
+
+func TestWithRetry(t *testing.T) {
+	r, attempts := flakyCommand(t, 2, 1)
+	output, err := command.WithRetry(r, command.RetryPolicy{MaxAttempts: 3}).RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts())
+	}
+	if output != "attempt 3\n" {
+		t.Errorf("expected only the output of the last attempt, got %q", output)
+	}
+}
+
+func TestWithRetryWritesEveryAttemptToCallerWriters(t *testing.T) {
+	r, _ := flakyCommand(t, 1, 1)
+	var stdout strings.Builder
+	if err := command.WithRetry(r, command.RetryPolicy{MaxAttempts: 2}).RunToWriter(&stdout, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "attempt 1\nattempt 2\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestWithRetryStopsOnUnmatchedErrors(t *testing.T) {
+	r, attempts := flakyCommand(t, 5, 1)
+	err := command.WithRetry(r, command.RetryPolicy{MaxAttempts: 5, RetryIf: command.RetryOnExitCode(75)}).Run()
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 1 {
+		t.Errorf("expected the error of the first attempt, got %v", err)
+	}
+	if attempts() != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts())
+	}
+}
+
+func TestWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	r, attempts := flakyCommand(t, 5, 75)
+	err := command.WithRetry(r, command.RetryPolicy{MaxAttempts: 3, RetryIf: command.RetryOnExitCode(75)}).Run()
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 75 {
+		t.Errorf("expected the error of the last attempt, got %v", err)
+	}
+	if attempts() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts())
+	}
+}
+
+func TestWithRetryWaitsForBackoff(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	r, attempts := flakyCommand(t, 1, 1)
+	retried := command.WithRetry(r.With(command.WithClock(clock)), command.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour})
+	done := make(chan error, 1)
+	go func() { done <- retried.Run() }()
+
+	clock.WaitForTimers(1)
+	if attempts() != 1 {
+		t.Fatalf("expected the retry to wait for the backoff, got %d attempts", attempts())
+	}
+	clock.Advance(time.Hour)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts() != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts())
+	}
+}
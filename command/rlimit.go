@@ -0,0 +1,48 @@
+package command
+
+type RLimitResource int
+
+const (
+	RLimitNoFile RLimitResource = iota
+	RLimitNProc
+	RLimitFSize
+	RLimitCore
+)
+
+type RLimit struct {
+	Resource RLimitResource
+	Soft     uint64
+	Hard     uint64
+}
+
+// WithRLimits applies resource limits to the started process, which is only
+// supported on Linux. Go cannot set them between fork and exec, so they are
+// set with prlimit right after the process starts: until then the process
+// runs unlimited and can, for instance, fork past RLimitNProc or write past
+// RLimitFSize. Wrap the command with prlimit or a shell running ulimit when
+// the limits must hold from the first instruction.
+func WithRLimits(limits ...RLimit) Option {
+	return func(r *commandRequest) {
+		r.rlimits = append(r.rlimits, limits...)
+	}
+}
+
+// CgroupLimits describes a cgroup v2 created for a single execution below
+// Parent, a cgroup directory the caller is allowed to manage. Zero values
+// leave the corresponding controller unlimited.
+type CgroupLimits struct {
+	Parent    string
+	MemoryMax int64
+	CPUs      float64
+	PidsMax   int
+}
+
+// WithCgroup runs the command inside a new cgroup v2 with the given limits,
+// the cgroup is removed once the command finishes. The command fails when it
+// cannot be removed, like when processes it started in the background are
+// still running. Linux only.
+func WithCgroup(limits CgroupLimits) Option {
+	return func(r *commandRequest) {
+		r.cgroup = &limits
+	}
+}
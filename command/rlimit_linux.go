@@ -0,0 +1,101 @@
+package command
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const cgroupCPUPeriod = 100000
+
+// cgroupRemoveAttempts bounds the removals of a cgroup, which fail with EBUSY
+// until the kernel is done releasing its exited processes. The delay between
+// them is real time, whatever the clock of the command, as it waits on the
+// kernel.
+const (
+	cgroupRemoveAttempts = 20
+	cgroupRemoveDelay    = 5 * time.Millisecond
+)
+
+var rlimitResources = map[RLimitResource]int{
+	RLimitNoFile: unix.RLIMIT_NOFILE,
+	RLimitNProc:  unix.RLIMIT_NPROC,
+	RLimitFSize:  unix.RLIMIT_FSIZE,
+	RLimitCore:   unix.RLIMIT_CORE,
+}
+
+func applyRLimits(req *commandRequest, pid int) error {
+	for _, limit := range req.rlimits {
+		resource, ok := rlimitResources[limit.Resource]
+		if !ok {
+			return fmt.Errorf("unknown resource limit %d", limit.Resource)
+		}
+		rlimit := &unix.Rlimit{Cur: limit.Soft, Max: limit.Hard}
+		if err := unix.Prlimit(pid, resource, rlimit, nil); err != nil {
+			return fmt.Errorf("failed to set resource limit %d: %w", limit.Resource, err)
+		}
+	}
+	return nil
+}
+
+func prepareCgroup(cmd *exec.Cmd, limits *CgroupLimits) (func() error, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(limits.Parent, "command-"+hex.EncodeToString(suffix))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	remove := func() error {
+		var err error
+		for attempt := 0; attempt < cgroupRemoveAttempts; attempt++ {
+			if err = os.Remove(dir); err == nil || errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if !errors.Is(err, syscall.EBUSY) {
+				break
+			}
+			time.Sleep(cgroupRemoveDelay)
+		}
+		return fmt.Errorf("failed to remove cgroup: %w", err)
+	}
+	settings := map[string]string{}
+	if limits.MemoryMax > 0 {
+		settings["memory.max"] = strconv.FormatInt(limits.MemoryMax, 10)
+	}
+	if limits.CPUs > 0 {
+		settings["cpu.max"] = fmt.Sprintf("%d %d", int64(limits.CPUs*cgroupCPUPeriod), cgroupCPUPeriod)
+	}
+	if limits.PidsMax > 0 {
+		settings["pids.max"] = strconv.Itoa(limits.PidsMax)
+	}
+	for file, value := range settings {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0o644); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to configure cgroup %s: %w", file, err), remove())
+		}
+	}
+	fd, err := syscall.Open(dir, syscall.O_DIRECTORY|syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open cgroup: %w", err), remove())
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	return func() error {
+		_ = syscall.Close(fd)
+		return remove()
+	}, nil
+}
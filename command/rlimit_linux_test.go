@@ -0,0 +1,77 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithRLimits(t *testing.T) {
+	out := startedOutput(t, "ulimit -n; ulimit -Hn", command.WithRLimits(command.RLimit{Resource: command.RLimitNoFile, Soft: 64, Hard: 128}))
+	if out != "64\n128" {
+		t.Errorf("unexpected file limits %q", out)
+	}
+}
+
+// cgroupParent returns the root of the cgroup v2 hierarchy, skipping the test
+// when it cannot be managed.
+func cgroupParent(t *testing.T) string {
+	t.Helper()
+	parent := "/sys/fs/cgroup"
+	if _, err := os.Stat(filepath.Join(parent, "unified", "cgroup.procs")); err == nil {
+		parent = filepath.Join(parent, "unified")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.procs")); err != nil || os.Geteuid() != 0 {
+		t.Skip("a writable cgroup v2 hierarchy is not available")
+	}
+	return parent
+}
+
+func TestWithCgroup(t *testing.T) {
+	parent := cgroupParent(t)
+	r := command.NewExecCmdFactory().Command(context.Background(), "cat", "/proc/self/cgroup").
+		With(command.WithCgroup(command.CgroupLimits{Parent: parent}))
+	out, err := r.RunStdout()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cgroup string
+	for _, line := range strings.Split(string(out), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			cgroup = path
+		}
+	}
+	name := filepath.Base(cgroup)
+	if !strings.HasPrefix(name, "command-") {
+		t.Fatalf("expected the command to run in its own cgroup, got %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(parent, name)); !os.IsNotExist(err) {
+		t.Errorf("expected the cgroup to be removed, got %v", err)
+	}
+}
+
+func TestWithCgroupReportsCgroupsLeftBehind(t *testing.T) {
+	parent := cgroupParent(t)
+	err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "sleep 1 >/dev/null 2>&1 &").
+		With(command.WithCgroup(command.CgroupLimits{Parent: parent})).
+		Run()
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, syscall.EBUSY) {
+		t.Fatalf("expected the busy cgroup to be reported, got %v", err)
+	}
+	t.Cleanup(func() {
+		// The cgroup is released once the background sleep exits.
+		deadline := time.Now().Add(5 * time.Second)
+		for os.Remove(pathErr.Path) != nil && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package command
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func applyRLimits(req *commandRequest, _ int) error {
+	if len(req.rlimits) > 0 {
+		return errors.New("resource limits are not supported on this platform")
+	}
+	return nil
+}
+
+func prepareCgroup(*exec.Cmd, *CgroupLimits) (func() error, error) {
+	return nil, errors.New("cgroups are not supported on this platform")
+}
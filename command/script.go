@@ -0,0 +1,140 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type ScriptPolicy int
+
+const (
+	StopOnError ScriptPolicy = iota
+	ContinueOnError
+	// RollbackOnError stops at the first failure and runs the undo steps of
+	// the steps already completed, most recent first.
+	RollbackOnError
+)
+
+type StepStatus int
+
+const (
+	StepSkipped StepStatus = iota
+	StepSucceeded
+	StepFailed
+	StepRolledBack
+	StepRollbackFailed
+)
+
+func (s StepStatus) String() string {
+	switch s {
+	case StepSucceeded:
+		return "succeeded"
+	case StepFailed:
+		return "failed"
+	case StepRolledBack:
+		return "rolled back"
+	case StepRollbackFailed:
+		return "rollback failed"
+	default:
+		return "skipped"
+	}
+}
+
+type StepReport struct {
+	Name   string
+	Status StepStatus
+	Result *Result
+	Err    error
+	// UndoResult and UndoErr describe the undo step when it was run.
+	UndoResult *Result
+	UndoErr    error
+}
+
+type ScriptReport struct {
+	Steps []StepReport
+}
+
+func (r *ScriptReport) Failed() bool {
+	for _, step := range r.Steps {
+		if step.Status == StepFailed || step.Status == StepRollbackFailed {
+			return true
+		}
+	}
+	return false
+}
+
+type scriptStep struct {
+	name string
+	run  Runnable
+	undo Runnable
+}
+
+type Script struct {
+	policy ScriptPolicy
+	steps  []scriptStep
+}
+
+func NewScript(policy ScriptPolicy) *Script {
+	return &Script{policy: policy}
+}
+
+func (s *Script) Step(name string, r Runnable) *Script {
+	return s.StepWithUndo(name, r, nil)
+}
+
+func (s *Script) StepWithUndo(name string, r Runnable, undo Runnable) *Script {
+	s.steps = append(s.steps, scriptStep{name: name, run: r, undo: undo})
+	return s
+}
+
+// Run executes the steps in order, ctx cancels the running step and skips the
+// remaining ones. The report holds an entry per step and the error joins the
+// failures of the steps and undo steps. With RollbackOnError a cancellation
+// also rolls back the completed steps, the undo steps running without the
+// cancellation of ctx: give them a timeout when they could hang.
+func (s *Script) Run(ctx context.Context) (*ScriptReport, error) {
+	report := &ScriptReport{Steps: make([]StepReport, len(s.steps))}
+	var errs []error
+	failed := false
+	for index, step := range s.steps {
+		stepReport := &report.Steps[index]
+		stepReport.Name = step.name
+		if ctx.Err() != nil || (failed && s.policy != ContinueOnError) {
+			continue
+		}
+		stepReport.Result, stepReport.Err = step.run.With(withCancel(ctx)).Execute()
+		if stepReport.Err == nil {
+			stepReport.Status = StepSucceeded
+			continue
+		}
+		stepReport.Status = StepFailed
+		errs = append(errs, fmt.Errorf("step %q: %w", step.name, stepReport.Err))
+		failed = true
+	}
+	if (failed || ctx.Err() != nil) && s.policy == RollbackOnError {
+		errs = append(errs, s.rollback(context.WithoutCancel(ctx), report)...)
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, context.Cause(ctx))
+	}
+	return report, errors.Join(errs...)
+}
+
+func (s *Script) rollback(ctx context.Context, report *ScriptReport) []error {
+	var errs []error
+	for index := len(s.steps) - 1; index >= 0; index-- {
+		step, stepReport := s.steps[index], &report.Steps[index]
+		if step.undo == nil || stepReport.Status != StepSucceeded {
+			continue
+		}
+		stepReport.UndoResult, stepReport.UndoErr = step.undo.With(withCancel(ctx)).Execute()
+		if stepReport.UndoErr != nil {
+			stepReport.Status = StepRollbackFailed
+			errs = append(errs, fmt.Errorf("undo of step %q: %w", step.name, stepReport.UndoErr))
+			continue
+		}
+		stepReport.Status = StepRolledBack
+	}
+	return errs
+}
@@ -0,0 +1,145 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// journal records the commands it runs, the ones named fail failing.
+type journal struct {
+	log runLog
+}
+
+func newJournal(t *testing.T) *journal {
+	return &journal{log: newRunLog(t)}
+}
+
+func (j *journal) step(name string) command.Runnable {
+	return j.log.command(context.Background(), name, `[ "$0" != fail ]`)
+}
+
+func (j *journal) ran() []string {
+	return j.log.entries()
+}
+
+// writerFunc is an io.Writer calling a function.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func statuses(report *command.ScriptReport) []command.StepStatus {
+	var result []command.StepStatus
+	for _, step := range report.Steps {
+		result = append(result, step.Status)
+	}
+	return result
+}
+
+func TestScriptStopOnError(t *testing.T) {
+	j := newJournal(t)
+	report, err := command.NewScript(command.StopOnError).
+		Step("a", j.step("a")).
+		Step("fail", j.step("fail")).
+		Step("c", j.step("c")).
+		Run(context.Background())
+	if err == nil || !report.Failed() {
+		t.Fatalf("expected the script to fail, got %v", err)
+	}
+	if ran := j.ran(); !slices.Equal(ran, []string{"a", "fail"}) {
+		t.Errorf("expected the script to stop at the failure, ran %q", ran)
+	}
+	expected := []command.StepStatus{command.StepSucceeded, command.StepFailed, command.StepSkipped}
+	if got := statuses(report); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestScriptContinueOnError(t *testing.T) {
+	j := newJournal(t)
+	report, err := command.NewScript(command.ContinueOnError).
+		Step("fail", j.step("fail")).
+		Step("b", j.step("b")).
+		Run(context.Background())
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 1 {
+		t.Fatalf("expected the failure to be reported, got %v", err)
+	}
+	expected := []command.StepStatus{command.StepFailed, command.StepSucceeded}
+	if got := statuses(report); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestScriptRollbackOnError(t *testing.T) {
+	j := newJournal(t)
+	report, err := command.NewScript(command.RollbackOnError).
+		StepWithUndo("a", j.step("a"), j.step("undo a")).
+		Step("b", j.step("b")).
+		StepWithUndo("c", j.step("c"), j.step("undo c")).
+		StepWithUndo("fail", j.step("fail"), j.step("undo fail")).
+		Run(context.Background())
+	if err == nil {
+		t.Fatal("expected the script to fail")
+	}
+	if ran := j.ran(); !slices.Equal(ran, []string{"a", "b", "c", "fail", "undo c", "undo a"}) {
+		t.Errorf("expected the completed steps to be undone in reverse, ran %q", ran)
+	}
+	expected := []command.StepStatus{command.StepRolledBack, command.StepSucceeded, command.StepRolledBack, command.StepFailed}
+	if got := statuses(report); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestScriptReportsFailedUndoSteps(t *testing.T) {
+	j := newJournal(t)
+	report, err := command.NewScript(command.RollbackOnError).
+		StepWithUndo("a", j.step("a"), j.step("fail")).
+		Step("b", j.step("fail")).
+		Run(context.Background())
+	if err == nil || report.Steps[0].Status != command.StepRollbackFailed || report.Steps[0].UndoErr == nil {
+		t.Errorf("expected the undo failure to be reported, got %v and %+v", err, report.Steps[0])
+	}
+}
+
+func TestScriptSkipsStepsOnceCanceled(t *testing.T) {
+	j := newJournal(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := command.NewScript(command.StopOnError).Step("a", j.step("a")).Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to be reported, got %v", err)
+	}
+	if len(j.ran()) != 0 || report.Steps[0].Status != command.StepSkipped {
+		t.Errorf("expected no step to run, ran %q", j.ran())
+	}
+}
+
+func TestScriptRollsBackOnceCanceled(t *testing.T) {
+	j := newJournal(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelling := command.NewExecCmdFactory().Command(context.Background(), "echo").
+		With(command.WithTee(writerFunc(func(p []byte) (int, error) {
+			cancel()
+			return len(p), nil
+		}), nil))
+	report, err := command.NewScript(command.RollbackOnError).
+		StepWithUndo("a", j.step("a"), j.step("undo a")).
+		Step("cancel", cancelling).
+		Step("c", j.step("c")).
+		Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to be reported, got %v", err)
+	}
+	if ran := j.ran(); !slices.Equal(ran, []string{"a", "undo a"}) {
+		t.Errorf("expected the completed steps to be undone, ran %q", ran)
+	}
+	if report.Steps[0].Status != command.StepRolledBack || report.Steps[0].UndoErr != nil {
+		t.Errorf("expected the undo step to run without the cancellation, got %+v", report.Steps[0])
+	}
+}
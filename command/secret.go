@@ -0,0 +1,87 @@
+package command
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+)
+
+const redacted = "***"
+
+// WithSecretArgs marks the arguments at the given positions, not counting the
+// command itself, as secret. Secret values are replaced by *** in errors and
+// dry-run output. Positions refer to the arguments given to the factory, also
+// for factories wrapping them in the command line of another program, like
+// Elevate or the shell factories.
+func WithSecretArgs(indices ...int) Option {
+	return func(r *commandRequest) {
+		args := r.args
+		if r.userArgs != nil {
+			args = r.userArgs
+		}
+		for _, index := range indices {
+			if index >= 0 && index < len(args) && args[index] != "" {
+				r.secretArgs = append(r.secretArgs, args[index])
+			}
+		}
+	}
+}
+
+// withUserArgs records the arguments given to a wrapping factory, the
+// positions of WithSecretArgs referring to them.
+func withUserArgs(args []string) Option {
+	return func(r *commandRequest) {
+		r.userArgs = append([]string{}, args...)
+	}
+}
+
+// WithSecretEnv marks the given environment variables as secret, see
+// WithSecretArgs.
+func WithSecretEnv(keys ...string) Option {
+	return func(r *commandRequest) {
+		r.secretEnv = append(r.secretEnv, keys...)
+	}
+}
+
+// redactedArgs replaces the secrets wherever they appear, as wrapping
+// factories may embed them in a larger argument.
+func (r *commandRequest) redactedArgs() []string {
+	args := slices.Clone(r.args)
+	for index := range args {
+		for _, secret := range r.secretArgs {
+			args[index] = strings.ReplaceAll(args[index], secret, redacted)
+		}
+	}
+	return args
+}
+
+func (r *commandRequest) redactedEnv(key, value string) string {
+	if slices.Contains(r.secretEnv, key) {
+		return redacted
+	}
+	return value
+}
+
+func (r *commandRequest) secretValues() [][]byte {
+	var values [][]byte
+	for _, secret := range r.secretArgs {
+		values = append(values, []byte(secret))
+	}
+	for _, key := range r.secretEnv {
+		for _, env := range []map[string]string{r.env, r.fileEnv} {
+			if value := env[key]; value != "" {
+				values = append(values, []byte(value))
+			}
+		}
+	}
+	return values
+}
+
+// redact replaces any secret value found in output, such as a tool echoing
+// its arguments in an error message.
+func (r *commandRequest) redact(output []byte) []byte {
+	for _, value := range r.secretValues() {
+		output = bytes.ReplaceAll(output, value, []byte(redacted))
+	}
+	return output
+}
@@ -0,0 +1,23 @@
+package command_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithSecretArgsInDryRun(t *testing.T) {
+	recorder := command.NewDryRunFactory(nil)
+	err := recorder.Command(context.Background(), "mysql", "-u", "root", "-pSECRET").
+		With(command.WithSecretArgs(2), command.WithEnv(map[string]string{"TOKEN": "hidden"}), command.WithSecretEnv("TOKEN")).
+		Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorded := recorder.Recorded()
+	if len(recorded) != 1 || strings.Contains(recorded[0], "SECRET") || strings.Contains(recorded[0], "hidden") {
+		t.Errorf("expected the secrets to be redacted, got %q", recorded)
+	}
+}
@@ -0,0 +1,178 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var ErrExpectTimeout = errors.New("expected output not received")
+
+var ErrSessionClosed = errors.New("session closed")
+
+// ErrSessionStdin is returned by StartSession for commands with a stdin
+// reader, the session being the one writing their input.
+var ErrSessionStdin = errors.New("session command already has a stdin reader")
+
+type Session struct {
+	process Process
+	stdin   *os.File
+	clock   Clock
+
+	mu      sync.Mutex
+	output  []byte
+	updated chan struct{}
+}
+
+// StartSession starts r with its stdin connected to the session so its output
+// can be matched with Expect and answered with Send. Combine it with WithPTY
+// for programs that only prompt when attached to a terminal. The timeouts of
+// Expect run on the clock of r. The tees set on r with WithTee keep receiving
+// the output, r cannot have a stdin reader though, StartSession fails with
+// ErrSessionStdin then.
+func StartSession(r Runnable) (*Session, error) {
+	var hasStdin bool
+	r.With(func(req *commandRequest) {
+		hasStdin = req.stdin != nil
+	})
+	if hasStdin {
+		return nil, ErrSessionStdin
+	}
+	// A file is used for stdin as exec would otherwise wait for its copy
+	// goroutine, which never ends while the session is open.
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{stdin: stdinWriter, clock: clockOf(r), updated: make(chan struct{})}
+	output := &sessionOutput{session: session}
+	process, err := r.With(WithStdinReader(stdinReader), withSessionTee(output)).Start()
+	if err != nil {
+		stdinReader.Close()
+		stdinWriter.Close()
+		return nil, err
+	}
+	session.process = process
+	go func() {
+		<-process.Done()
+		stdinReader.Close()
+		session.notify()
+	}()
+	return session, nil
+}
+
+// withSessionTee adds output to the tees of the command, next to the ones it
+// already has.
+func withSessionTee(output io.Writer) Option {
+	return func(r *commandRequest) {
+		if r.teeStdout != nil {
+			r.teeStdout = io.MultiWriter(r.teeStdout, output)
+		} else {
+			r.teeStdout = output
+		}
+		if r.teeStderr != nil {
+			r.teeStderr = io.MultiWriter(r.teeStderr, output)
+		} else {
+			r.teeStderr = output
+		}
+	}
+}
+
+type sessionOutput struct {
+	session *Session
+}
+
+func (o *sessionOutput) Write(p []byte) (int, error) {
+	s := o.session
+	s.mu.Lock()
+	s.output = append(s.output, p...)
+	s.mu.Unlock()
+	s.notify()
+	return len(p), nil
+}
+
+func (s *Session) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+func (s *Session) Process() Process {
+	return s.process
+}
+
+func (s *Session) Send(text string) error {
+	select {
+	case <-s.process.Done():
+		return ErrSessionClosed
+	default:
+	}
+	_, err := io.WriteString(s.stdin, text)
+	if errors.Is(err, os.ErrClosed) || isBrokenPipe(err) {
+		return ErrSessionClosed
+	}
+	return err
+}
+
+func (s *Session) SendLine(line string) error {
+	return s.Send(line + "\n")
+}
+
+// Expect waits until the output not consumed by previous calls matches pattern
+// and returns the match and its groups. Output up to the end of the match is
+// consumed.
+func (s *Session) Expect(pattern *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	deadline := s.clock.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		updated := s.updated
+		if loc := pattern.FindSubmatchIndex(s.output); loc != nil {
+			groups := make([]string, len(loc)/2)
+			for index := range groups {
+				if loc[2*index] >= 0 {
+					groups[index] = string(s.output[loc[2*index]:loc[2*index+1]])
+				}
+			}
+			s.output = s.output[loc[1]:]
+			s.mu.Unlock()
+			return groups, nil
+		}
+		pending := string(s.output)
+		s.mu.Unlock()
+
+		select {
+		case <-s.process.Done():
+			if s.matchesAfterExit(pattern) {
+				continue
+			}
+			err := fmt.Errorf("%w: %q not found before exit in %q", ErrSessionClosed, pattern, pending)
+			return nil, errors.Join(err, s.process.Wait())
+		case <-deadline.C():
+			return nil, fmt.Errorf("%w: %q not found in %q after %s", ErrExpectTimeout, pattern, pending, timeout)
+		case <-updated:
+		}
+	}
+}
+
+func (s *Session) matchesAfterExit(pattern *regexp.Regexp) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return pattern.Match(s.output)
+}
+
+func (s *Session) ExpectString(text string, timeout time.Duration) error {
+	_, err := s.Expect(regexp.MustCompile(regexp.QuoteMeta(text)), timeout)
+	return err
+}
+
+// Close closes the session stdin and waits for the command to finish.
+func (s *Session) Close() error {
+	s.stdin.Close()
+	return s.process.Wait()
+}
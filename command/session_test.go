@@ -0,0 +1,108 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestSessionExpectTimesOutWithClock(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session, err := command.StartSession(command.NewExecCmdFactory(command.WithDefaultOptions(command.WithClock(clock))).Command(ctx, "cat"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := make(chan error, 1)
+	go func() {
+		expected <- session.ExpectString("never printed", time.Hour)
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Hour)
+	select {
+	case err := <-expected:
+		if !errors.Is(err, command.ErrExpectTimeout) {
+			t.Errorf("expected ErrExpectTimeout, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expect did not time out with the clock")
+	}
+}
+
+func TestSessionDialog(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `printf "name? "; read name; echo "hello $name"`)
+	session, err := command.StartSession(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := session.ExpectString("name? ", 5*time.Second); err != nil {
+		t.Fatalf("prompt not received: %v", err)
+	}
+	if err := session.SendLine("bob"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	groups, err := session.Expect(regexp.MustCompile(`hello (\w+)`), 5*time.Second)
+	if err != nil {
+		t.Fatalf("answer not received: %v", err)
+	}
+	if groups[1] != "bob" {
+		t.Errorf("unexpected groups %q", groups)
+	}
+	if err := session.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := session.Send("late"); !errors.Is(err, command.ErrSessionClosed) {
+		t.Errorf("expected ErrSessionClosed once exited, got %v", err)
+	}
+}
+
+func TestSessionExpectReportsExit(t *testing.T) {
+	session, err := command.StartSession(command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo bye; exit 2"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = session.ExpectString("never printed", time.Minute)
+	if !errors.Is(err, command.ErrSessionClosed) {
+		t.Errorf("expected ErrSessionClosed, got %v", err)
+	}
+	var cmdErr *command.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode() != 2 {
+		t.Errorf("expected the exit code of the command, got %v", err)
+	}
+}
+
+func TestSessionKeepsTheTeesOfTheCommand(t *testing.T) {
+	var tee strings.Builder
+	r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo out; echo err >&2").
+		With(command.WithTee(&tee, nil))
+	session, err := command.StartSession(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := session.ExpectString("err", 5*time.Second); err != nil {
+		t.Fatalf("output not received: %v", err)
+	}
+	if err := session.Process().Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tee.String() != "out\n" {
+		t.Errorf("expected the stdout of the command in its tee, got %q", tee.String())
+	}
+}
+
+func TestSessionRejectsStdinReaders(t *testing.T) {
+	r := command.NewExecCmdFactory().Command(context.Background(), "cat").
+		With(command.WithStdinReader(strings.NewReader("input")))
+	if _, err := command.StartSession(r); !errors.Is(err, command.ErrSessionStdin) {
+		t.Errorf("expected ErrSessionStdin, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type ShellKind int
+
+const (
+	ShellDefault ShellKind = iota
+	ShellSh
+	ShellBash
+	ShellCmd
+	ShellPowerShell
+)
+
+func DefaultShellKind() ShellKind {
+	if runtime.GOOS == "windows" {
+		return ShellCmd
+	}
+	return ShellSh
+}
+
+func (k ShellKind) executable() string {
+	switch k {
+	case ShellBash:
+		return "bash"
+	case ShellCmd:
+		return "cmd.exe"
+	case ShellPowerShell:
+		if runtime.GOOS == "windows" {
+			return "powershell.exe"
+		}
+		return "pwsh"
+	case ShellDefault:
+		return DefaultShellKind().executable()
+	default:
+		return "sh"
+	}
+}
+
+func shellKindOf(shell string) ShellKind {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(shell), filepath.Ext(shell)))
+	switch name {
+	case "cmd":
+		return ShellCmd
+	case "powershell", "pwsh":
+		return ShellPowerShell
+	case "bash":
+		return ShellBash
+	default:
+		return ShellSh
+	}
+}
+
+type shellCmdFactory struct {
+	shell string
+	kind  ShellKind
+}
+
+// NewShellCmdFactory returns a factory whose commands are scripts run through
+// the given shell, additional arguments are passed as the positional
+// parameters of the script. The kind of shell is detected from its name and
+// an empty shell selects the platform default. Arguments of cmd.exe scripts
+// holding characters it cannot quote fail with ErrUnsafeCmdArgument.
+func NewShellCmdFactory(shell string) CommandFactory {
+	if shell == "" {
+		return NewShellKindCmdFactory(ShellDefault)
+	}
+	return &shellCmdFactory{shell: shell, kind: shellKindOf(shell)}
+}
+
+func NewShellKindCmdFactory(kind ShellKind) CommandFactory {
+	if kind == ShellDefault {
+		kind = DefaultShellKind()
+	}
+	return &shellCmdFactory{shell: kind.executable(), kind: kind}
+}
+
+func (f *shellCmdFactory) Command(ctx context.Context, script string, args ...string) Runnable {
+	req := commandRequest{ctx: ctx, cmd: f.shell}
+	withUserArgs(args)(&req)
+	switch f.kind {
+	case ShellCmd:
+		// cmd.exe has no positional parameters, arguments are appended to the
+		// script line and the raw line is handed over unescaped on Windows.
+		line := script
+		for index, arg := range args {
+			if unsafe := strings.IndexAny(arg, cmdUnsafeChars); unsafe >= 0 {
+				req.err = fmt.Errorf("%w: argument %d contains %q", ErrUnsafeCmdArgument, index, arg[unsafe])
+				break
+			}
+			line += " " + quoteCmdArg(arg)
+		}
+		req.args = []string{"/S", "/C", line}
+		req.rawCmdLine = QuoteWindows(f.shell) + ` /S /C "` + line + `"`
+	case ShellPowerShell:
+		line := "& {" + script + "}"
+		for _, arg := range args {
+			line += " " + quotePowerShellArg(arg)
+		}
+		req.args = []string{"-NoProfile", "-NonInteractive", "-Command", line}
+	default:
+		req.args = append([]string{"-c", script, f.shell}, args...)
+	}
+	return &execCommand{commandRequest: req}
+}
+
+// ErrUnsafeCmdArgument is returned for the arguments of cmd.exe scripts that
+// cannot be quoted: cmd.exe has no escape for a double quote inside quotes
+// and expands variables even there.
+var ErrUnsafeCmdArgument = errors.New("argument cannot be passed safely to cmd.exe")
+
+// cmdUnsafeChars end the quoting of an argument, expand variables, the
+// exclamation mark with delayed expansion, or end the command line.
+const cmdUnsafeChars = "\"%!\r\n"
+
+func quoteCmdArg(arg string) string {
+	return quoteWindows(arg, strings.ContainsAny(arg, "&|<>^%()"))
+}
+
+func quotePowerShellArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", "''") + "'"
+}
@@ -0,0 +1,18 @@
+package command
+
+import (
+	"context"
+	"testing"
+)
+
+func TestShellCmdRawCommandLine(t *testing.T) {
+	for shell, expected := range map[string]string{
+		`cmd.exe`:                         `cmd.exe /S /C "echo "a b""`,
+		`C:/Program Files/Shells/cmd.exe`: `"C:/Program Files/Shells/cmd.exe" /S /C "echo "a b""`,
+	} {
+		r := NewShellCmdFactory(shell).Command(context.Background(), "echo", "a b").(*execCommand)
+		if r.rawCmdLine != expected {
+			t.Errorf("%s: expected %s, got %s", shell, expected, r.rawCmdLine)
+		}
+	}
+}
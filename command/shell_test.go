@@ -0,0 +1,86 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// shellArgs returns the arguments a shell factory gives to the shell, which
+// is not started: cmd.exe is missing on unix hosts.
+func shellArgs(t *testing.T, r command.Runnable) []string {
+	t.Helper()
+	var args []string
+	_ = r.With(command.WithCmdCustomizer(func(cmd *exec.Cmd) { args = cmd.Args[1:] })).Run()
+	if args == nil {
+		t.Fatal("the command was not prepared")
+	}
+	return args
+}
+
+func TestShellCmdRejectsUnsafeArguments(t *testing.T) {
+	factory := command.NewShellKindCmdFactory(command.ShellCmd)
+	for _, arg := range []string{`a" & echo pwned & "`, "%PATH%", "!PATH!", "a\r\necho pwned"} {
+		r := factory.Command(context.Background(), "echo", arg)
+		if !errors.Is(r.Err(), command.ErrUnsafeCmdArgument) {
+			t.Errorf("argument %q: expected ErrUnsafeCmdArgument, got %v", arg, r.Err())
+		}
+		if err := r.Run(); !errors.Is(err, command.ErrUnsafeCmdArgument) {
+			t.Errorf("argument %q: expected Run to fail with ErrUnsafeCmdArgument, got %v", arg, err)
+		}
+	}
+}
+
+func TestShellCmdQuotesArguments(t *testing.T) {
+	r := command.NewShellKindCmdFactory(command.ShellCmd).Command(context.Background(), "echo", "a & b", `C:\dir\`, "plain")
+	if err := r.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"/S", "/C", `echo "a & b" C:\dir\ plain`}
+	if args := shellArgs(t, r); !slices.Equal(args, expected) {
+		t.Errorf("expected arguments %q, got %q", expected, args)
+	}
+}
+
+func TestShellPowerShellQuotesArguments(t *testing.T) {
+	r := command.NewShellKindCmdFactory(command.ShellPowerShell).Command(context.Background(), "Write-Output $args", "it's", "$HOME")
+	expected := []string{"-NoProfile", "-NonInteractive", "-Command", `& {Write-Output $args} 'it''s' '$HOME'`}
+	if args := shellArgs(t, r); !slices.Equal(args, expected) {
+		t.Errorf("expected arguments %q, got %q", expected, args)
+	}
+}
+
+func TestShellCmdFactoryPassesPositionalArguments(t *testing.T) {
+	r := command.NewShellCmdFactory("").Command(context.Background(), `echo "$#:$1:$2"; echo "$0" >&2`, "a b", "$HOME")
+	stdout, err := r.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout != "2:a b:$HOME\n" {
+		t.Errorf("expected the arguments as positional parameters, got %q", stdout)
+	}
+}
+
+func TestShellSecretArgs(t *testing.T) {
+	err := command.NewShellKindCmdFactory(command.ShellSh).
+		Command(context.Background(), `echo "denied $1" >&2; exit 1`, "-pSECRET").
+		With(command.WithSecretArgs(0)).
+		Run()
+	var commandErr *command.CommandError
+	if !errors.As(err, &commandErr) {
+		t.Fatalf("expected a CommandError, got %v", err)
+	}
+	expected := []string{"-c", `echo "denied $1" >&2; exit 1`, "sh", "***"}
+	if args := commandErr.Args(); !slices.Equal(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+	if stderr := string(commandErr.Stderr()); stderr != "denied ***\n" {
+		t.Errorf("expected the secret to be redacted from stderr, got %q", stderr)
+	}
+}
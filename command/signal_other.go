@@ -0,0 +1,19 @@
+//go:build !windows
+
+package command
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func prepareSignals(*exec.Cmd) {}
+
+func (p *execProcess) Interrupt() error {
+	return p.Signal(os.Interrupt)
+}
+
+func (p *execProcess) Terminate() error {
+	return p.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// prepareSignals starts the process in its own console process group, the
+// only way for it to be targeted by CTRL_BREAK events.
+func prepareSignals(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_NEW_PROCESS_GROUP
+}
+
+func (p *execProcess) Interrupt() error {
+	return p.whileRunning(func() error {
+		return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.current.Pid))
+	})
+}
+
+func (p *execProcess) Terminate() error {
+	return p.Kill()
+}
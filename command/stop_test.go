@@ -0,0 +1,157 @@
+//go:build unix
+
+package command_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+// runUntilReady runs script, which prints ready once set up, and cancels its
+// context at that point. It returns the printed lines and the time the
+// command took to stop after the cancellation.
+func runUntilReady(t *testing.T, script string, opts ...command.Option) ([]string, time.Duration, error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lines []string
+	var canceled time.Time
+	err := command.NewExecCmdFactory().Command(ctx, "sh", "-c", script).With(opts...).RunStream(func(line string) {
+		lines = append(lines, line)
+		if line == "ready" {
+			canceled = time.Now()
+			cancel()
+		}
+	}, nil)
+	if canceled.IsZero() {
+		t.Fatalf("the command did not get ready: %v", err)
+	}
+	return lines, time.Since(canceled), err
+}
+
+func TestWithGracefulStop(t *testing.T) {
+	lines, _, err := runUntilReady(t, `trap 'echo stopped; exit 0' TERM; echo ready; while :; do sleep 0.01; done`,
+		command.WithGracefulStop(syscall.SIGTERM, 10*time.Second))
+	if !slices.Equal(lines, []string{"ready", "stopped"}) {
+		t.Errorf("expected the command to handle the signal, got %q", lines)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error, got %v", err)
+	}
+}
+
+func TestWithGracefulStopKillsAfterGracePeriod(t *testing.T) {
+	_, elapsed, err := runUntilReady(t, `trap '' TERM; echo ready; while :; do sleep 0.01; done`,
+		command.WithGracefulStop(syscall.SIGTERM, 100*time.Millisecond))
+	if elapsed > 5*time.Second {
+		t.Errorf("expected the command to be killed after the grace period, it stopped after %s", elapsed)
+	}
+	if err == nil {
+		t.Error("expected the killed command to fail")
+	}
+}
+
+func TestWithGracefulStopRejectsNonPositiveGracePeriods(t *testing.T) {
+	for _, gracePeriod := range []time.Duration{0, -time.Second} {
+		r := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", `trap '' TERM; echo ran`).
+			With(command.WithGracefulStop(syscall.SIGTERM, gracePeriod))
+		if r.Err() == nil {
+			t.Errorf("%s: expected the grace period to be rejected", gracePeriod)
+		}
+		if output, err := r.RunStdoutStr(); err == nil || output != "" {
+			t.Errorf("%s: expected the command not to run, got %q and %v", gracePeriod, output, err)
+		}
+	}
+}
+
+// exited reports whether the process pid is gone or a zombie waiting to be
+// reaped by its new parent.
+func exited(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	// The state follows the command name, which is between parentheses.
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+func TestWithProcessGroupStopsDescendants(t *testing.T) {
+	lines, elapsed, err := runUntilReady(t, `sleep 30 & echo $!; echo ready; wait`, command.WithProcessGroup())
+	if err == nil {
+		t.Error("expected the killed command to fail")
+	}
+	// Without the group the background sleep would keep stdout open, and Run
+	// waiting, for its 30 seconds.
+	if elapsed > 5*time.Second {
+		t.Errorf("expected the command to stop with its descendants, it stopped after %s", elapsed)
+	}
+	pid, convErr := strconv.Atoi(lines[0])
+	if convErr != nil {
+		t.Fatalf("unexpected output %q", lines)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !exited(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("descendant %d still running", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunScanStopsTheCommandOnError(t *testing.T) {
+	failure := errors.New("enough")
+	start := time.Now()
+	err := command.NewExecCmdFactory().Command(context.Background(), "sh", "-c", "echo a; exec sleep 30").
+		RunScan(bufio.ScanLines, func([]byte) error {
+			return failure
+		})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the handler error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the command to be stopped, took %s", elapsed)
+	}
+}
+
+func TestWithProcessGroupKillsAfterTheGracePeriodOfTheClock(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- command.NewExecCmdFactory().Command(ctx, "sh", "-c", `trap '' TERM; echo ready; while :; do sleep 0.01; done`).
+			With(command.WithProcessGroup(), command.WithGracefulStop(syscall.SIGTERM, time.Hour), command.WithClock(clock)).
+			RunStream(func(line string) {
+				if line == "ready" {
+					cancel()
+				}
+			}, nil)
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Hour)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the killed command to fail")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the process group was not killed once the clock reached the grace period")
+	}
+}
@@ -0,0 +1,127 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+const defaultMaxLineSize = 64 << 10
+
+// WithMaxLineSize bounds the lines handed to line callbacks, longer lines are
+// delivered in pieces of at most size bytes.
+func WithMaxLineSize(size int) Option {
+	return func(r *commandRequest) {
+		r.maxLineSize = size
+	}
+}
+
+type lineWriter struct {
+	mu      *sync.Mutex
+	maxSize int
+	onLine  func(line string)
+	pending []byte
+}
+
+func newLineWriter(mu *sync.Mutex, maxSize int, onLine func(line string)) *lineWriter {
+	if maxSize <= 0 {
+		maxSize = defaultMaxLineSize
+	}
+	return &lineWriter{mu: mu, maxSize: maxSize, onLine: onLine}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		index := bytes.IndexByte(p, '\n')
+		if index < 0 {
+			w.pending = append(w.pending, p...)
+			for len(w.pending) >= w.maxSize {
+				w.emit(w.pending[:w.maxSize])
+				w.pending = w.pending[w.maxSize:]
+			}
+			break
+		}
+		line := append(w.pending, p[:index]...)
+		for len(line) > w.maxSize {
+			w.emit(line[:w.maxSize])
+			line = line[w.maxSize:]
+		}
+		w.emit(line)
+		w.pending = w.pending[:0]
+		p = p[index+1:]
+	}
+	return n, nil
+}
+
+func (w *lineWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.emit(w.pending)
+		w.pending = w.pending[:0]
+	}
+}
+
+func (w *lineWriter) emit(line []byte) {
+	if w.onLine == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onLine(string(bytes.TrimSuffix(line, []byte("\r"))))
+}
+
+// RunStream invokes the callbacks with each line written to stdout and stderr
+// while the command runs. Callbacks are never called concurrently and either of
+// them can be nil.
+func (e *execCommand) RunStream(onStdoutLine func(line string), onStderrLine func(line string)) error {
+	var mu sync.Mutex
+	stdout := newLineWriter(&mu, e.maxLineSize, onStdoutLine)
+	stderr := newLineWriter(&mu, e.maxLineSize, onStderrLine)
+	err := e.run(&streams{stdout: stdout, stderr: stderr})
+	stdout.Flush()
+	stderr.Flush()
+	return err
+}
+
+// RunScan feeds stdout, while the command runs, to a bufio.Scanner using split
+// and calls handle with each token. Tokens are bounded by WithMaxLineSize. An
+// error from handle stops the command and is returned.
+func (e *execCommand) RunScan(split bufio.SplitFunc, handle func(token []byte) error) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	reader, writer := io.Pipe()
+	scanned := make(chan error, 1)
+	go func() {
+		maxSize := e.maxLineSize
+		if maxSize <= 0 {
+			maxSize = defaultMaxLineSize
+		}
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, min(maxSize, 4096)), maxSize)
+		scanner.Split(split)
+		var err error
+		for err == nil && scanner.Scan() {
+			err = handle(scanner.Bytes())
+		}
+		if err == nil {
+			err = scanner.Err()
+		}
+		if err != nil {
+			cancel(err)
+		}
+		// Further writes fail instead of blocking the command, which also
+		// releases exec from waiting on processes left holding stdout.
+		reader.CloseWithError(err)
+		scanned <- err
+	}()
+	derived := &execCommand{commandRequest: e.commandRequest.clone()}
+	withCancel(ctx)(&derived.commandRequest)
+	err := derived.run(&streams{stdout: writer})
+	writer.Close()
+	if scanErr := <-scanned; scanErr != nil {
+		return scanErr
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package command_test
+
+import (
+	"bufio"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestRunStream(t *testing.T) {
+	r := streamsCommand("first\r\nsec", "!warning\n", "ond\nunterminated")
+	var stdout, stderr []string
+	err := r.RunStream(func(line string) { stdout = append(stdout, line) }, func(line string) { stderr = append(stderr, line) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(stdout, []string{"first", "second", "unterminated"}) {
+		t.Errorf("unexpected stdout lines %q", stdout)
+	}
+	if !slices.Equal(stderr, []string{"warning"}) {
+		t.Errorf("unexpected stderr lines %q", stderr)
+	}
+}
+
+func TestRunStreamSplitsLongLines(t *testing.T) {
+	r := outputCommand("abcdefgh\nij\n").With(command.WithMaxLineSize(3))
+	var lines []string
+	if err := r.RunStream(func(line string) { lines = append(lines, line) }, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(lines, []string{"abc", "def", "gh", "ij"}) {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
+
+func TestRunScan(t *testing.T) {
+	var words []string
+	err := outputCommand("one two\nthree").RunScan(bufio.ScanWords, func(token []byte) error {
+		words = append(words, string(token))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(words, ",") != "one,two,three" {
+		t.Errorf("unexpected tokens %q", words)
+	}
+}
+
+func TestRunScanBoundsTokens(t *testing.T) {
+	err := outputCommand(strings.Repeat("x", 100)+"\n").With(command.WithMaxLineSize(10)).RunScan(bufio.ScanLines, func([]byte) error {
+		return nil
+	})
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("expected bufio.ErrTooLong, got %v", err)
+	}
+}
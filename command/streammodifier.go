@@ -0,0 +1,86 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// WithStreamModifiers applies modifiers to each line of stdout and stderr as
+// the command writes it, so they also act on streamed output. Lines that end
+// up empty are dropped, which lets line filters work as expected. Modifier
+// failures follow WithModifierErrorPolicy, ModifierFailFast dropping the line,
+// and are returned once the command finishes.
+func WithStreamModifiers(modifiers ...RunnablePostModifier) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			var writers []*modifierWriter
+			err := withLineWriters(s, func(w io.Writer, _ OutputStream) flushWriter {
+				writer := &modifierWriter{writer: w, modifiers: modifiers, policy: req.modifierPolicy}
+				writers = append(writers, writer)
+				return writer
+			}, func() error {
+				return next(req, s)
+			})
+			errs := []error{err}
+			for _, writer := range writers {
+				errs = append(errs, writer.errs...)
+			}
+			return errors.Join(errs...)
+		}
+	})
+}
+
+type modifierWriter struct {
+	writer    io.Writer
+	modifiers []RunnablePostModifier
+	policy    ModifierErrorPolicy
+	pending   []byte
+	errs      []error
+}
+
+func (w *modifierWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		index := bytes.IndexByte(p, '\n')
+		if index < 0 {
+			w.pending = append(w.pending, p...)
+			if len(w.pending) >= defaultMaxLineSize {
+				if err := w.Flush(); err != nil {
+					return n, err
+				}
+			}
+			break
+		}
+		w.pending = append(w.pending, p[:index]...)
+		if err := w.emit(true); err != nil {
+			return n, err
+		}
+		p = p[index+1:]
+	}
+	return n, nil
+}
+
+func (w *modifierWriter) Flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	return w.emit(false)
+}
+
+func (w *modifierWriter) emit(newline bool) error {
+	line := string(w.pending)
+	w.pending = w.pending[:0]
+	modified, err := applyModifiers(line, w.modifiers, w.policy)
+	if err != nil {
+		w.errs = append(w.errs, err)
+	}
+	if line != "" && modified == "" {
+		return nil
+	}
+	if newline {
+		modified += "\n"
+	}
+	_, err = io.WriteString(w.writer, modified)
+	return err
+}
@@ -0,0 +1,45 @@
+package command_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithStreamModifiers(t *testing.T) {
+	var lines []string
+	r := streamsCommand("keep a\ndrop b\n", "!keep c\n", "keep d").
+		With(command.WithStreamModifiers(command.NewLinePrefixFilterPostModifier("keep"), command.NewToUpperPostModifier()))
+	err := r.RunStream(func(line string) {
+		lines = append(lines, line)
+	}, func(line string) {
+		lines = append(lines, "stderr "+line)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(lines, ",") != "KEEP A,stderr KEEP C,KEEP D" {
+		t.Errorf("expected the streamed lines to be modified, got %q", lines)
+	}
+}
+
+func TestWithStreamModifiersReportsFailures(t *testing.T) {
+	failure := errors.New("rejected")
+	reject := command.PostModifierFunc(func(content string) (string, error) {
+		if content == "bad" {
+			return content, failure
+		}
+		return content, nil
+	})
+	var stdout bytes.Buffer
+	err := streamsCommand("good\nbad\nfine\n").With(command.WithStreamModifiers(reject)).RunToWriter(&stdout, nil)
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the failure once the command finished, got %v", err)
+	}
+	if stdout.String() != "good\nfine\n" {
+		t.Errorf("expected the failed line to be dropped, got %q", stdout.String())
+	}
+}
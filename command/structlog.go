@@ -0,0 +1,271 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	structuredLevelKeys   = []string{"level", "lvl", "severity"}
+	structuredMessageKeys = []string{"msg", "message"}
+	structuredTimeKeys    = []string{"time", "ts", "timestamp"}
+)
+
+// WithStructuredLogs sends every line of stdout and stderr to logger. Lines
+// holding a JSON object or logfmt pairs become records with their level,
+// message and time, the remaining fields being kept as attributes, other lines
+// are logged as is at info level. Every record has a stream attribute and its
+// secrets redacted. The output is still captured or written as usual. Attributes of the context,
+// see ContextWithLogAttrs, and of WithLogAttrs are added to every record.
+func WithStructuredLogs(logger *slog.Logger) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			stdout, stderr := s.stdout, s.stderr
+			defer func() { s.stdout, s.stderr = stdout, stderr }()
+			handler := logger.Handler()
+			if attrs := append(logAttrsFromContext(req.ctx), req.logAttrs...); len(attrs) > 0 {
+				handler = handler.WithAttrs(attrs)
+			}
+			if req.logGroup != "" {
+				handler = handler.WithGroup(req.logGroup)
+			}
+			clock := req.clockOrDefault()
+			stdoutLog := &structuredLogWriter{ctx: req.ctx, handler: handler, clock: clock, redact: req.redact, stream: StreamStdout}
+			stderrLog := &structuredLogWriter{ctx: req.ctx, handler: handler, clock: clock, redact: req.redact, stream: StreamStderr}
+			s.tee(stdoutLog, stderrLog)
+			err := next(req, s)
+			stdoutLog.flush()
+			stderrLog.flush()
+			return err
+		}
+	})
+}
+
+type logAttrsKey struct{}
+
+// ContextWithLogAttrs returns a context whose commands add the given
+// attributes, as slog.Logger.With takes them, to the records of
+// WithStructuredLogs. Attributes already in ctx are kept.
+func ContextWithLogAttrs(ctx context.Context, args ...any) context.Context {
+	attrs := append(logAttrsFromContext(ctx), slog.Group("", args...).Value.Group()...)
+	return context.WithValue(ctx, logAttrsKey{}, attrs)
+}
+
+func logAttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return slices.Clone(attrs)
+}
+
+// WithLogAttrs adds attributes, as slog.Logger.With takes them, to the records
+// of WithStructuredLogs.
+func WithLogAttrs(args ...any) Option {
+	return func(r *commandRequest) {
+		r.logAttrs = append(r.logAttrs, slog.Group("", args...).Value.Group()...)
+	}
+}
+
+// WithLogGroup puts the fields of the output lines and the stream attribute of
+// the records of WithStructuredLogs in a group, apart from the attributes of
+// the caller.
+func WithLogGroup(name string) Option {
+	return func(r *commandRequest) {
+		r.logGroup = name
+	}
+}
+
+type structuredLogWriter struct {
+	ctx     context.Context
+	handler slog.Handler
+	clock   Clock
+	redact  func([]byte) []byte
+	stream  OutputStream
+	pending []byte
+}
+
+func (w *structuredLogWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		index := bytes.IndexByte(p, '\n')
+		if index < 0 {
+			w.pending = append(w.pending, p...)
+			if len(w.pending) >= defaultMaxLineSize {
+				w.flush()
+			}
+			break
+		}
+		w.pending = append(w.pending, p[:index]...)
+		w.flush()
+		p = p[index+1:]
+	}
+	return n, nil
+}
+
+func (w *structuredLogWriter) flush() {
+	line := strings.TrimSuffix(string(w.pending), "\r")
+	w.pending = w.pending[:0]
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	line = string(w.redact([]byte(line)))
+	fields, ok := parseJSONLogLine(line)
+	if !ok {
+		fields, _ = parseLogfmtLine(line)
+	}
+	// Escaped secrets only show once the values are decoded.
+	for index := range fields {
+		fields[index].value = redactLogValue(fields[index].value, w.redact)
+	}
+	record := newStructuredRecord(line, fields, w.clock.Now())
+	if !w.handler.Enabled(w.ctx, record.Level) {
+		return
+	}
+	record.AddAttrs(slog.String("stream", w.stream.String()))
+	// Failures of the handler are not the command's, the record is dropped.
+	_ = w.handler.Handle(w.ctx, record)
+}
+
+type logField struct {
+	key   string
+	value any
+}
+
+// newStructuredRecord returns the record of line, stamped with now unless its
+// fields hold a time.
+func newStructuredRecord(line string, fields []logField, now time.Time) slog.Record {
+	level := slog.LevelInfo
+	message := line
+	stamp := now
+	var attrs []slog.Attr
+	if fields != nil {
+		message = ""
+		levelFound, messageFound, timeFound := false, false, false
+		for _, field := range fields {
+			text, isString := field.value.(string)
+			switch {
+			case !levelFound && isString && slices.Contains(structuredLevelKeys, field.key):
+				if parsed, ok := parseLogLevel(text); ok {
+					level, levelFound = parsed, true
+					continue
+				}
+			case !messageFound && isString && slices.Contains(structuredMessageKeys, field.key):
+				message, messageFound = text, true
+				continue
+			case !timeFound && isString && slices.Contains(structuredTimeKeys, field.key):
+				if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+					stamp, timeFound = parsed, true
+					continue
+				}
+			}
+			attrs = append(attrs, slog.Any(field.key, field.value))
+		}
+	}
+	record := slog.NewRecord(stamp, level, message, 0)
+	record.AddAttrs(attrs...)
+	return record
+}
+
+func redactLogValue(value any, redact func([]byte) []byte) any {
+	switch value := value.(type) {
+	case string:
+		return string(redact([]byte(value)))
+	case map[string]any:
+		for key, nested := range value {
+			value[key] = redactLogValue(nested, redact)
+		}
+	case []any:
+		for index, nested := range value {
+			value[index] = redactLogValue(nested, redact)
+		}
+	}
+	return value
+}
+
+func parseLogLevel(text string) (slog.Level, bool) {
+	switch strings.ToLower(text) {
+	case "trace":
+		return slog.LevelDebug - 4, true
+	case "debug", "dbug":
+		return slog.LevelDebug, true
+	case "info", "information", "notice":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error", "err", "eror":
+		return slog.LevelError, true
+	case "fatal", "panic", "critical", "crit", "alert", "emergency":
+		return slog.LevelError + 4, true
+	}
+	return 0, false
+}
+
+func parseJSONLogLine(line string) ([]logField, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil || decoder.More() {
+		return nil, false
+	}
+	fields := make([]logField, 0, len(object))
+	for key, value := range object {
+		fields = append(fields, logField{key: key, value: value})
+	}
+	slices.SortFunc(fields, func(a, b logField) int { return strings.Compare(a.key, b.key) })
+	return fields, true
+}
+
+// parseLogfmtLine parses key=value pairs, values being bare or double quoted.
+// Only lines made of pairs only and holding a level or a message are taken as
+// logfmt, to avoid mistaking plain text for it.
+func parseLogfmtLine(line string) ([]logField, bool) {
+	var fields []logField
+	known := false
+	rest := strings.TrimSpace(line)
+	for rest != "" {
+		keyEnd := strings.IndexAny(rest, "= \t\"")
+		if keyEnd <= 0 || rest[keyEnd] != '=' {
+			return nil, false
+		}
+		key := rest[:keyEnd]
+		rest = rest[keyEnd+1:]
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, false
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return nil, false
+			}
+			rest = rest[len(quoted):]
+			if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+				return nil, false
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		if slices.Contains(structuredLevelKeys, key) || slices.Contains(structuredMessageKeys, key) {
+			known = true
+		}
+		fields = append(fields, logField{key: key, value: value})
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	if !known {
+		return nil, false
+	}
+	return fields, true
+}
@@ -0,0 +1,131 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// logRecords returns the records written by a JSON handler to buffer.
+func logRecords(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWithStructuredLogs(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	out, err := streamsCommand(
+		`{"level":"warn","msg":"disk low","time":"2024-01-02T03:04:05Z","free":12}`+"\n",
+		"!level=error msg=\"write failed\" path=/tmp\n",
+		"plain text\n",
+	).With(command.WithStructuredLogs(logger)).RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(out, "plain text\n") {
+		t.Errorf("expected the output to be captured as usual, got %q", out)
+	}
+	records := logRecords(t, &buffer)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %v", records)
+	}
+	expected := []map[string]any{
+		{"level": "WARN", "msg": "disk low", "time": "2024-01-02T03:04:05Z", "free": 12.0, "stream": "stdout"},
+		{"level": "ERROR", "msg": "write failed", "path": "/tmp", "stream": "stderr"},
+		{"level": "INFO", "msg": "plain text", "stream": "stdout"},
+	}
+	for index, fields := range expected {
+		for key, value := range fields {
+			if records[index][key] != value {
+				t.Errorf("record %d: expected %s=%v, got %v", index, key, value, records[index])
+			}
+		}
+	}
+}
+
+func TestWithStructuredLogsHonorsTheLevel(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	err := streamsCommand("level=debug msg=noise\n", "level=warn msg=kept\n").With(command.WithStructuredLogs(logger)).Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records := logRecords(t, &buffer); len(records) != 1 || records[0]["msg"] != "kept" {
+		t.Errorf("expected only the warning, got %v", records)
+	}
+}
+
+func TestWithStructuredLogsAttrs(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+	ctx := command.ContextWithLogAttrs(context.Background(), "request_id", "r1")
+	ctx = command.ContextWithLogAttrs(ctx, slog.String("tenant", "acme"))
+	err := command.NewFuncFactory(func(inv *command.Invocation) error {
+		_, err := io.WriteString(inv.Stdout, "level=info msg=done count=2\n")
+		return err
+	}).Command(ctx, "job").With(
+		command.WithStructuredLogs(logger),
+		command.WithLogAttrs("component", "worker"),
+		command.WithLogGroup("child"),
+	).Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := logRecords(t, &buffer)
+	if len(records) != 1 {
+		t.Fatalf("expected a record, got %v", records)
+	}
+	record := records[0]
+	if record["request_id"] != "r1" || record["tenant"] != "acme" || record["component"] != "worker" {
+		t.Errorf("expected the caller attributes at the top level, got %v", record)
+	}
+	child, _ := record["child"].(map[string]any)
+	if child["count"] != "2" || child["stream"] != "stdout" {
+		t.Errorf("expected the output fields in the group, got %v", record)
+	}
+}
+
+func TestWithStructuredLogsRedactsSecrets(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+	err := command.NewFuncFactory(func(inv *command.Invocation) error {
+		secret, _ := json.Marshal(inv.Args[0])
+		_, err := io.WriteString(inv.Stdout, "login "+inv.Args[0]+"\n"+
+			`{"msg":"retry","password":`+string(secret)+`,"auth":{"token":`+string(secret)+`}}`+"\n")
+		return err
+	}).Command(context.Background(), "login", `pa"ss`).With(
+		command.WithSecretArgs(0),
+		command.WithStructuredLogs(logger),
+	).Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buffer.String(), `pa\"ss`) {
+		t.Errorf("expected the secret to be redacted, got %s", buffer.String())
+	}
+	records := logRecords(t, &buffer)
+	if len(records) != 2 || records[0]["msg"] != "login ***" || records[1]["password"] != "***" {
+		t.Errorf("expected redacted records, got %v", records)
+	}
+	if auth, _ := records[1]["auth"].(map[string]any); auth["token"] != "***" {
+		t.Errorf("expected the nested value to be redacted, got %v", records[1])
+	}
+}
@@ -0,0 +1,65 @@
+//go:build unix
+
+package command_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+// fakeSudo puts a sudo in PATH running the command after asking the askpass
+// helper for the password or reading it from stdin, or straight away when prompt is false as sudo does
+// for NOPASSWD rules. It returns the path of an askpass helper printing the
+// password it is given.
+func fakeSudo(t *testing.T, prompt bool) string {
+	t.Helper()
+	dir := t.TempDir()
+	ask := ""
+	if prompt {
+		ask = `[ "$1" = -A ] && { [ "$("$SUDO_ASKPASS")" = pw ] || exit 9; }; [ "$1" = -S ] && { read -r p; [ "$p" = pw ] || exit 9; };`
+	}
+	script := "#!/bin/sh\nwhile [ \"$1\" != -- ]; do " + ask + " shift; done\nshift\nexec \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "sudo"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	helper := "#!/bin/sh\nprintf '%s\\n' \"$ASKPASS_PASSWORD\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "askpass"), []byte(helper), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return filepath.Join(dir, "askpass")
+}
+
+func TestElevatePasswordThroughStdin(t *testing.T) {
+	fakeSudo(t, true)
+	out, err := command.Elevate(command.NewExecCmdFactory(), command.ElevateOptions{Password: "pw"}).
+		Command(context.Background(), "cat").
+		RunWithInputStr("input")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "input" {
+		t.Errorf("expected the command to read only its input, got %q", out)
+	}
+}
+
+func TestElevatePasswordThroughAskPass(t *testing.T) {
+	for name, prompt := range map[string]bool{"prompting": true, "not prompting": false} {
+		t.Run(name, func(t *testing.T) {
+			helper := fakeSudo(t, prompt)
+			out, err := command.Elevate(command.NewExecCmdFactory(), command.ElevateOptions{AskPassHelper: helper, Password: "pw"}).
+				Command(context.Background(), "cat").
+				RunWithInputStr("input")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != "input" {
+				t.Errorf("expected the command to read only its input, got %q", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var tableHeaderSeparator = regexp.MustCompile(`\s{2,}`)
+
+type TableOptions struct {
+	// Columns names the columns, when empty they are read from the first
+	// line of the output.
+	Columns []string
+	// SkipHeader drops the first line when Columns is given.
+	SkipHeader bool
+	// FixedWidth cuts each line where the header columns start, so values can
+	// contain spaces, and reads header names separated by two or more spaces.
+	// Otherwise lines are split on whitespace and the last column receives the
+	// rest of the line.
+	FixedWidth bool
+}
+
+// RunTable runs r and parses its output with ParseTable.
+func RunTable(r Runnable, opts TableOptions) ([]map[string]string, error) {
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		return nil, err
+	}
+	return ParseTable(output, opts)
+}
+
+// ParseTable parses tabular output like the one of df, docker ps or kubectl
+// get into a map per row keyed by column name.
+func ParseTable(output string, opts TableOptions) ([]map[string]string, error) {
+	lines, _ := splitLines(strings.ReplaceAll(output, "\r\n", "\n"))
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return []map[string]string{}, nil
+	}
+	columns := opts.Columns
+	var starts []int
+	if len(columns) == 0 || opts.FixedWidth {
+		if len(columns) > 0 && !opts.SkipHeader {
+			return nil, errors.New("fixed width tables need a header line to find the columns")
+		}
+		header := lines[0]
+		lines = lines[1:]
+		names, positions := tableHeader(header, opts.FixedWidth)
+		if len(columns) == 0 {
+			columns = names
+		} else if len(columns) != len(names) {
+			return nil, errors.New("the number of columns does not match the header")
+		}
+		starts = positions
+	} else if opts.SkipHeader {
+		lines = lines[1:]
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("table has no columns")
+	}
+	rows := make([]map[string]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var values []string
+		if opts.FixedWidth {
+			values = splitFixedWidth(line, starts)
+		} else {
+			values = splitFieldsN(line, len(columns))
+		}
+		row := make(map[string]string, len(columns))
+		for index, column := range columns {
+			if index < len(values) {
+				row[column] = values[index]
+			} else {
+				row[column] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func tableHeader(header string, fixedWidth bool) ([]string, []int) {
+	var names []string
+	var starts []int
+	if !fixedWidth {
+		return strings.Fields(header), nil
+	}
+	offset := len(header) - len(strings.TrimLeft(header, " \t"))
+	for _, name := range tableHeaderSeparator.Split(strings.TrimSpace(header), -1) {
+		index := strings.Index(header[offset:], name)
+		names = append(names, name)
+		// Positions are kept in runes as that is how columns are aligned.
+		starts = append(starts, utf8.RuneCountInString(header[:offset+index]))
+		offset += index + len(name)
+	}
+	return names, starts
+}
+
+// splitFixedWidth cuts line at starts, moving a cut left to the previous space
+// when a value crosses it, as right aligned columns often do.
+func splitFixedWidth(text string, starts []int) []string {
+	line := []rune(text)
+	values := make([]string, len(starts))
+	for index := range starts {
+		begin := adjustCut(line, starts[index])
+		end := len(line)
+		if index+1 < len(starts) {
+			end = adjustCut(line, starts[index+1])
+		}
+		if begin < end {
+			values[index] = strings.TrimSpace(string(line[begin:end]))
+		}
+	}
+	return values
+}
+
+func adjustCut(line []rune, cut int) int {
+	if cut <= 0 {
+		return 0
+	}
+	if cut >= len(line) {
+		return len(line)
+	}
+	for cut > 0 && line[cut-1] != ' ' && line[cut] != ' ' {
+		cut--
+	}
+	return cut
+}
+
+// splitFieldsN splits line on whitespace into at most n fields, the last one
+// holding the rest of the line.
+func splitFieldsN(line string, n int) []string {
+	var fields []string
+	rest := strings.TrimSpace(line)
+	for rest != "" {
+		if len(fields) == n-1 {
+			return append(fields, rest)
+		}
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return append(fields, rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return fields
+}
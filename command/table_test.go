@@ -0,0 +1,84 @@
+package command_test
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func checkRows(t *testing.T, rows []map[string]string, expected []map[string]string) {
+	t.Helper()
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %v", len(expected), rows)
+	}
+	for index := range expected {
+		if !maps.Equal(rows[index], expected[index]) {
+			t.Errorf("row %d: expected %v, got %v", index, expected[index], rows[index])
+		}
+	}
+}
+
+func TestParseTableWhitespace(t *testing.T) {
+	output := "NAME   READY   COMMAND\nweb    1/1     nginx -g daemon off\ndb     0/1\n"
+	rows, err := command.ParseTable(output, command.TableOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkRows(t, rows, []map[string]string{
+		{"NAME": "web", "READY": "1/1", "COMMAND": "nginx -g daemon off"},
+		{"NAME": "db", "READY": "0/1", "COMMAND": ""},
+	})
+}
+
+func TestParseTableFixedWidth(t *testing.T) {
+	output := "CONTAINER ID   IMAGE          STATUS\n" +
+		"abc123         nginx:latest   Up 2 hours\n" +
+		"def456         redis          Exited (0) 1 day ago\n"
+	rows, err := command.ParseTable(output, command.TableOptions{FixedWidth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkRows(t, rows, []map[string]string{
+		{"CONTAINER ID": "abc123", "IMAGE": "nginx:latest", "STATUS": "Up 2 hours"},
+		{"CONTAINER ID": "def456", "IMAGE": "redis", "STATUS": "Exited (0) 1 day ago"},
+	})
+}
+
+func TestParseTableFixedWidthRightAligned(t *testing.T) {
+	output := "Filesystem  Size  Mounted on\n/dev/sda1   100G  /\ntmpfs      1024M  /tmp\n"
+	rows, err := command.ParseTable(output, command.TableOptions{FixedWidth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkRows(t, rows, []map[string]string{
+		{"Filesystem": "/dev/sda1", "Size": "100G", "Mounted on": "/"},
+		{"Filesystem": "tmpfs", "Size": "1024M", "Mounted on": "/tmp"},
+	})
+}
+
+func TestParseTableColumns(t *testing.T) {
+	rows, err := command.ParseTable("a 1\nb 2\n", command.TableOptions{Columns: []string{"name", "value"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkRows(t, rows, []map[string]string{{"name": "a", "value": "1"}, {"name": "b", "value": "2"}})
+
+	rows, err = command.ParseTable("NAME VALUE\na 1\n", command.TableOptions{Columns: []string{"name", "value"}, SkipHeader: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkRows(t, rows, []map[string]string{{"name": "a", "value": "1"}})
+
+	if _, err := command.ParseTable("a 1\n", command.TableOptions{Columns: []string{"name"}, FixedWidth: true}); err == nil {
+		t.Error("expected fixed width columns without a header to fail")
+	}
+}
+
+func TestRunTable(t *testing.T) {
+	rows, err := command.RunTable(outputCommand("\nA  B\n1  2\n"), command.TableOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkRows(t, rows, []map[string]string{{"A": "1", "B": "2"}})
+}
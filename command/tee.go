@@ -0,0 +1,51 @@
+package command
+
+import (
+	"io"
+	"sync"
+)
+
+// WithTee streams the command output to the given writers in addition to
+// whatever the Run method does with it. Either writer can be nil.
+func WithTee(stdout io.Writer, stderr io.Writer) Option {
+	return func(r *commandRequest) {
+		r.teeStdout = stdout
+		r.teeStderr = stderr
+	}
+}
+
+type lockedWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer.Write(p)
+}
+
+func teeWriter(w io.Writer, tee io.Writer) io.Writer {
+	if tee == nil {
+		return w
+	}
+	if w == nil {
+		return tee
+	}
+	return io.MultiWriter(w, tee)
+}
+
+func (s *streams) tee(stdout io.Writer, stderr io.Writer) {
+	if sameWriter(s.stdout, s.stderr) {
+		if sameWriter(stdout, stderr) {
+			shared := teeWriter(s.stdout, stdout)
+			s.stdout, s.stderr = shared, shared
+			return
+		}
+		// Splitting a combined capture means it is written from two streams.
+		shared := &lockedWriter{writer: s.stdout}
+		s.stdout, s.stderr = shared, shared
+	}
+	s.stdout = teeWriter(s.stdout, stdout)
+	s.stderr = teeWriter(s.stderr, stderr)
+}
@@ -0,0 +1,29 @@
+package command_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestWithTee(t *testing.T) {
+	var teeStdout, teeStderr strings.Builder
+	r := streamsCommand("out\n", "!err\n").With(command.WithTee(&teeStdout, &teeStderr))
+	output, err := r.RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "out\n" || teeStdout.String() != "out\n" || teeStderr.String() != "err\n" {
+		t.Errorf("unexpected output %q, tee %q and %q", output, teeStdout.String(), teeStderr.String())
+	}
+
+	var teeCombined strings.Builder
+	combined, err := streamsCommand("out\n", "!err\n").With(command.WithTee(&teeCombined, nil)).RunCombinedStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if combined != "out\nerr\n" || teeCombined.String() != "out\n" {
+		t.Errorf("expected the combined capture and stdout in the tee, got %q and %q", combined, teeCombined.String())
+	}
+}
@@ -0,0 +1,168 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+type CommandTemplate struct {
+	factory CommandFactory
+	words   []*template.Template
+}
+
+// Template parses a command line whose words are text/template templates.
+// The line is split into words once, like a POSIX shell would, before any
+// rendering so rendered values always end up in a single argument no matter
+// their content. Actions can contain spaces and words can be quoted.
+func Template(text string) (*CommandTemplate, error) {
+	words, err := splitTemplateWords(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.New("empty command template")
+	}
+	t := &CommandTemplate{}
+	for index, word := range words {
+		parsed, err := template.New(fmt.Sprintf("word%d", index)).Option("missingkey=error").Parse(word)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse command template: %w", err)
+		}
+		t.words = append(t.words, parsed)
+	}
+	return t, nil
+}
+
+func (t *CommandTemplate) Factory(factory CommandFactory) *CommandTemplate {
+	t.factory = factory
+	return t
+}
+
+// Render returns the command and its arguments for the given data.
+func (t *CommandTemplate) Render(data any) ([]string, error) {
+	words := make([]string, len(t.words))
+	var rendered strings.Builder
+	for index, word := range t.words {
+		rendered.Reset()
+		if err := word.Execute(&rendered, data); err != nil {
+			return nil, fmt.Errorf("failed to render command template: %w", err)
+		}
+		words[index] = rendered.String()
+	}
+	return words, nil
+}
+
+func (t *CommandTemplate) Command(ctx context.Context, data any) Runnable {
+	factory := t.factory
+	if factory == nil {
+		factory = NewExecCmdFactory()
+	}
+	words, err := t.Render(data)
+	if err != nil {
+		return &execCommand{commandRequest: commandRequest{ctx: ctx, err: err}}
+	}
+	return factory.Command(ctx, words[0], words[1:]...)
+}
+
+func splitTemplateWords(text string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+	for index := 0; index < len(text); index++ {
+		char := text[index]
+		switch {
+		case strings.HasPrefix(text[index:], "{{"):
+			end := strings.Index(text[index:], "}}")
+			if end < 0 {
+				return nil, errors.New("unterminated action in command template")
+			}
+			word.WriteString(text[index : index+end+2])
+			index += end + 1
+			inWord = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else if char == '\\' && quote == '"' && index+1 < len(text) && strings.IndexByte(`"\`, text[index+1]) >= 0 {
+				index++
+				word.WriteByte(text[index])
+			} else {
+				word.WriteByte(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inWord = true
+		case char == '\\' && index+1 < len(text):
+			index++
+			word.WriteByte(text[index])
+			inWord = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(char)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in command template")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+type TemplateInput int
+
+const (
+	TemplateInputRaw TemplateInput = iota
+	TemplateInputJSON
+	TemplateInputYAML
+)
+
+type templatePostModifier struct {
+	template *template.Template
+	err      error
+	input    TemplateInput
+}
+
+// NewTemplatePostModifier renders text with the output as data, either the
+// raw string or the value decoded from JSON or YAML.
+func NewTemplatePostModifier(text string, input TemplateInput) *templatePostModifier {
+	parsed, err := template.New("output").Option("missingkey=error").Parse(text)
+	return &templatePostModifier{template: parsed, err: err, input: input}
+}
+
+func (m *templatePostModifier) process(content string) (string, error) {
+	if m.err != nil {
+		return content, fmt.Errorf("invalid output template: %w", m.err)
+	}
+	var data any = content
+	switch m.input {
+	case TemplateInputJSON:
+		decoder := json.NewDecoder(strings.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return content, newDecodeError("json", []byte(content), jsonErrorOffset(err), err)
+		}
+	case TemplateInputYAML:
+		if err := yaml.Unmarshal([]byte(content), &data); err != nil {
+			return content, newDecodeError("yaml", []byte(content), yamlErrorOffset([]byte(content), err), err)
+		}
+	}
+	var rendered strings.Builder
+	if err := m.template.Execute(&rendered, data); err != nil {
+		return content, fmt.Errorf("failed to render output template: %w", err)
+	}
+	return rendered.String(), nil
+}
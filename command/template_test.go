@@ -0,0 +1,91 @@
+package command_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pablintino/commons-go/command"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tmpl, err := command.Template(`git commit -m "{{ .Message }}" --author='{{.Name}} <{{.Email}}>' a\ b`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	words, err := tmpl.Render(map[string]string{"Message": "fix: a b; rm -rf /", "Name": "Jane Doe", "Email": "jane@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"git", "commit", "-m", "fix: a b; rm -rf /", "--author=Jane Doe <jane@example.com>", "a b"}
+	if !slices.Equal(words, expected) {
+		t.Errorf("expected %q, got %q", expected, words)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	for _, text := range []string{"", "echo {{ .A", `echo "a`, "echo {{ .A | nope }}"} {
+		if _, err := command.Template(text); err == nil {
+			t.Errorf("%q: expected a parse error", text)
+		}
+	}
+	tmpl, err := command.Template("echo {{ .Missing }}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tmpl.Render(map[string]string{}); err == nil {
+		t.Error("expected a missing key to fail the rendering")
+	}
+	if err := tmpl.Command(context.Background(), map[string]string{}).Run(); err == nil {
+		t.Error("expected the command of a failed rendering to fail")
+	}
+}
+
+func TestTemplateCommand(t *testing.T) {
+	recorder := command.NewDryRunFactory(nil)
+	tmpl, err := command.Template("kubectl get pod {{ .Pod }} -n {{ .Namespace }}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tmpl.Factory(recorder).Command(context.Background(), map[string]string{"Pod": "web 1", "Namespace": "prod"}).Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recorded := recorder.Recorded(); len(recorded) != 1 || recorded[0] != "kubectl get pod 'web 1' -n prod" {
+		t.Errorf("unexpected command %q", recorded)
+	}
+}
+
+func TestTemplatePostModifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		text     string
+		input    command.TemplateInput
+		expected string
+	}{
+		{name: "raw", output: "abc", text: "<{{ . }}>", input: command.TemplateInputRaw, expected: "<abc>"},
+		{name: "json", output: `{"items": [{"name": "a"}, {"name": "b"}]}`, text: "{{ range .items }}{{ .name }} {{ end }}", input: command.TemplateInputJSON, expected: "a b "},
+		{name: "yaml", output: "name: a\ncount: 2\n", text: "{{ .name }}={{ .count }}", input: command.TemplateInputYAML, expected: "a=2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := outputCommand(test.output).RunStdoutStr(command.NewTemplatePostModifier(test.text, test.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestTemplatePostModifierErrors(t *testing.T) {
+	if _, err := outputCommand("{").RunStdoutStr(command.NewTemplatePostModifier("{{ . }}", command.TemplateInputJSON)); err == nil {
+		t.Error("expected invalid JSON to fail")
+	}
+	if _, err := outputCommand("a").RunStdoutStr(command.NewTemplatePostModifier("{{ .", command.TemplateInputRaw)); err == nil || !strings.Contains(err.Error(), "invalid output template") {
+		t.Errorf("expected an invalid template error, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+type TimestampFormat int
+
+const (
+	// TimestampRFC3339 prefixes lines with their local time in RFC 3339 format
+	// with millisecond precision.
+	TimestampRFC3339 TimestampFormat = iota
+	// TimestampRelative prefixes lines with the time elapsed since the writer
+	// was created.
+	TimestampRelative
+)
+
+const rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
+// NewTimestampWriter prefixes each line with the time its first byte was
+// written.
+func NewTimestampWriter(w io.Writer, format TimestampFormat) *PrefixWriter {
+	return newTimestampWriter(w, format, systemClock{})
+}
+
+func newTimestampWriter(w io.Writer, format TimestampFormat, clock Clock) *PrefixWriter {
+	created := clock.Now()
+	return &PrefixWriter{writer: w, clock: clock, prefix: func(lineStart time.Time) string {
+		if format == TimestampRelative {
+			return fmt.Sprintf("+%.3fs ", lineStart.Sub(created).Seconds())
+		}
+		return lineStart.Format(rfc3339Milli) + " "
+	}}
+}
+
+// WithOutputTimestamps prefixes every line of stdout and stderr with a
+// timestamp, relative ones count from the start of the execution.
+func WithOutputTimestamps(format TimestampFormat) Option {
+	return withMiddleware(func(next execFunc) execFunc {
+		return func(req *commandRequest, s *streams) error {
+			return withLineWriters(s, func(w io.Writer, _ OutputStream) flushWriter {
+				return newTimestampWriter(w, format, req.clockOrDefault())
+			}, func() error {
+				return next(req, s)
+			})
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pablintino/commons-go/command"
+	"github.com/pablintino/commons-go/command/commandtest"
+)
+
+func TestTimestampWriter(t *testing.T) {
+	tests := map[command.TimestampFormat]*regexp.Regexp{
+		command.TimestampRFC3339:  regexp.MustCompile(`^(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}(Z|[+-]\d\d:\d\d) line\n){2}$`),
+		command.TimestampRelative: regexp.MustCompile(`^(\+\d+\.\d{3}s line\n){2}$`),
+	}
+	for format, expected := range tests {
+		var out bytes.Buffer
+		writer := command.NewTimestampWriter(&out, format)
+		if _, err := writer.Write([]byte("line\nline\n")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !expected.MatchString(out.String()) {
+			t.Errorf("%d: unexpected output %q", format, out.String())
+		}
+	}
+}
+
+func TestWithOutputTimestamps(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := streamsCommand("out\n", "!err").With(command.WithOutputTimestamps(command.TimestampRelative)).RunToWriter(&stdout, &stderr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`^\+\d+\.\d{3}s out\n$`).MatchString(stdout.String()) || !regexp.MustCompile(`^\+\d+\.\d{3}s err$`).MatchString(stderr.String()) {
+		t.Errorf("unexpected output %q and %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestWithOutputTimestampsUsesClock(t *testing.T) {
+	clock := commandtest.NewFakeClock(time.Now())
+	r := command.NewFuncFactory(func(inv *command.Invocation) error {
+		clock.Advance(time.Second)
+		io.WriteString(inv.Stdout, "first\n")
+		clock.Advance(1500 * time.Millisecond)
+		io.WriteString(inv.Stdout, "second\n")
+		return nil
+	}).Command(context.Background(), "log")
+	out, err := r.With(command.WithClock(clock), command.WithOutputTimestamps(command.TimestampRelative)).RunStdoutStr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "+1.000s first\n+2.500s second\n" {
+		t.Errorf("expected timestamps from the clock, got %q", out)
+	}
+}